@@ -0,0 +1,289 @@
+package pip
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/paketo-buildpacks/packit/v2/postal"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+	"github.com/paketo-buildpacks/packit/v2/servicebindings"
+)
+
+// An advisory is a vulnerability in the OSV format
+// (https://ossf.github.io/osv-schema/), limited to the fields that are needed
+// to match it against the versions of PyPI distributions.
+type advisory struct {
+	ID       string `json:"id"`
+	Affected []struct {
+		Package struct {
+			Ecosystem string `json:"ecosystem"`
+			Name      string `json:"name"`
+		} `json:"package"`
+		Ranges []struct {
+			Type   string `json:"type"`
+			Events []struct {
+				Introduced   string `json:"introduced"`
+				Fixed        string `json:"fixed"`
+				LastAffected string `json:"last_affected"`
+			} `json:"events"`
+		} `json:"ranges"`
+		Versions []string `json:"versions"`
+	} `json:"affected"`
+}
+
+// loadAdvisories reads the OSV advisories provided by the given bindings of
+// type pip-advisories and by the file or directory at $BP_PIP_ADVISORIES,
+// relative to the working directory. No network access is required.
+func loadAdvisories(bindings []servicebindings.Binding, workingDir string) ([]advisory, error) {
+	var advisories []advisory
+	for _, binding := range bindings {
+		var names []string
+		for name := range binding.Entries {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			content, err := binding.Entries[name].ReadBytes()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read advisories '%s' from binding '%s': %w", name, binding.Name, err)
+			}
+
+			parsed, err := parseAdvisories(content)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse advisories '%s' from binding '%s': %w", name, binding.Name, err)
+			}
+			advisories = append(advisories, parsed...)
+		}
+	}
+
+	path := os.Getenv("BP_PIP_ADVISORIES")
+	if path == "" {
+		return advisories, nil
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(workingDir, path)
+	}
+
+	files := []string{path}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		files, err = filepath.Glob(filepath.Join(path, "*.json"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list BP_PIP_ADVISORIES: %w", err)
+		}
+	}
+
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read BP_PIP_ADVISORIES: %w", err)
+		}
+
+		parsed, err := parseAdvisories(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse BP_PIP_ADVISORIES %s: %w", file, err)
+		}
+		advisories = append(advisories, parsed...)
+	}
+
+	return advisories, nil
+}
+
+// parseAdvisories parses a single OSV advisory, a list of advisories, or the
+// response of the OSV query API ({"vulns": [...]}).
+func parseAdvisories(content []byte) ([]advisory, error) {
+	var list []advisory
+	if err := json.Unmarshal(content, &list); err == nil {
+		return list, nil
+	}
+
+	var document struct {
+		advisory
+		Vulns []advisory `json:"vulns"`
+	}
+	err := json.Unmarshal(content, &document)
+	if err != nil {
+		return nil, err
+	}
+
+	if document.ID != "" {
+		return append(document.Vulns, document.advisory), nil
+	}
+
+	return document.Vulns, nil
+}
+
+// affects reports whether the advisory applies to the given version of the
+// PyPI distribution with the given normalized name. An error is returned when
+// the version cannot be compared with the bounds of an affected range.
+func (a advisory) affects(name, version string) (bool, error) {
+	for _, affected := range a.Affected {
+		if !strings.EqualFold(affected.Package.Ecosystem, "PyPI") || normalizeName(affected.Package.Name) != name {
+			continue
+		}
+
+		// Explicitly listed versions that cannot be parsed only match the
+		// version when they are written in the same way.
+		for _, v := range affected.Versions {
+			if comparison, err := compareVersions(v, version); v == version || (err == nil && comparison == 0) {
+				return true, nil
+			}
+		}
+
+		for _, r := range affected.Ranges {
+			if r.Type != "ECOSYSTEM" && r.Type != "SEMVER" {
+				continue
+			}
+
+			// The events of a range are ordered by version: the version is
+			// affected after an introduced event until a fixed or last_affected
+			// event excludes it.
+			inRange := false
+			for _, event := range r.Events {
+				switch {
+				case event.Introduced == "0":
+					inRange = true
+				case event.Introduced != "":
+					comparison, err := compareVersions(version, event.Introduced)
+					if err != nil {
+						return false, err
+					}
+					if comparison >= 0 {
+						inRange = true
+					}
+				case event.Fixed != "":
+					comparison, err := compareVersions(version, event.Fixed)
+					if err != nil {
+						return false, err
+					}
+					if comparison >= 0 {
+						inRange = false
+					}
+				case event.LastAffected != "":
+					comparison, err := compareVersions(version, event.LastAffected)
+					if err != nil {
+						return false, err
+					}
+					if comparison > 0 {
+						inRange = false
+					}
+				}
+			}
+
+			if inRange {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// checkAdvisories logs the advisories that affect any of the given
+// dependencies. With the fail policy, an error listing the advisories is
+// returned instead of a warning. Advisories that cannot be matched against a
+// dependency, e.g. because of a version that cannot be parsed, are skipped
+// with a warning.
+func checkAdvisories(logger scribe.Emitter, advisories []advisory, policy string, dependencies []postal.Dependency) error {
+	if len(advisories) == 0 {
+		return nil
+	}
+
+	var findings, skipped []string
+	for _, dependency := range dependencies {
+		name := normalizeName(dependency.ID)
+		if name == "" {
+			name = normalizeName(dependency.Name)
+		}
+
+		seen := map[string]bool{}
+		var ids []string
+		for _, a := range advisories {
+			if seen[a.ID] {
+				continue
+			}
+
+			affected, err := a.affects(name, dependency.Version)
+			if err != nil {
+				skipped = append(skipped, fmt.Sprintf("%s for %s %s: %s", a.ID, name, dependency.Version, err))
+				continue
+			}
+
+			if affected {
+				seen[a.ID] = true
+				ids = append(ids, a.ID)
+			}
+		}
+
+		if len(ids) > 0 {
+			sort.Strings(ids)
+			findings = append(findings, fmt.Sprintf("%s %s (%s)", name, dependency.Version, strings.Join(ids, ", ")))
+		}
+	}
+
+	if len(skipped) > 0 {
+		logger.Process("Warning: skipped advisories that could not be checked")
+		for _, advisory := range skipped {
+			logger.Subprocess(advisory)
+		}
+		logger.Break()
+	}
+
+	if len(findings) == 0 {
+		return nil
+	}
+
+	if policy == FailVulnerabilityPolicy {
+		return fmt.Errorf("found known vulnerabilities with BP_PIP_VULN_POLICY=%s: %s", policy, strings.Join(findings, "; "))
+	}
+
+	logger.Process("Warning: found known vulnerabilities")
+	for _, finding := range findings {
+		logger.Subprocess(finding)
+	}
+	logger.Break()
+
+	return nil
+}
+
+// vulnerabilityPolicy returns the policy given by $BP_PIP_VULN_POLICY, which
+// defaults to warn.
+func vulnerabilityPolicy() (string, error) {
+	policy := os.Getenv("BP_PIP_VULN_POLICY")
+	switch policy {
+	case "":
+		return WarnVulnerabilityPolicy, nil
+	case WarnVulnerabilityPolicy, FailVulnerabilityPolicy:
+		return policy, nil
+	}
+
+	return "", fmt.Errorf("unsupported BP_PIP_VULN_POLICY %q: expected %q or %q", policy, WarnVulnerabilityPolicy, FailVulnerabilityPolicy)
+}
+
+// normalizeName normalizes a PyPI project name (PEP 503).
+func normalizeName(name string) string {
+	return strings.ToLower(separatorPattern.ReplaceAllString(name, "-"))
+}
+
+// compareVersions compares two PEP 440 versions once they are translated into
+// semver versions. An error is returned when either cannot be translated, e.g.
+// a post-release or a version with more than three release segments.
+func compareVersions(a, b string) (int, error) {
+	va, err := semver.NewVersion(semverVersion(a))
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse version %q: %w", a, err)
+	}
+
+	vb, err := semver.NewVersion(semverVersion(b))
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse version %q: %w", b, err)
+	}
+
+	return va.Compare(vb), nil
+}
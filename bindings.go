@@ -0,0 +1,80 @@
+package pip
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/paketo-buildpacks/packit/v2"
+	"github.com/paketo-buildpacks/packit/v2/servicebindings"
+)
+
+// configurePipBinding points invocations of pip in downstream buildpacks at
+// the pip.conf, and optional .netrc, provided by a service binding of type
+// pip. The files are referenced where the platform mounted them so that their
+// contents are never copied into a layer.
+func configurePipBinding(layer *packit.Layer, binding servicebindings.Binding) error {
+	if _, ok := binding.Entries[PipConfigFile]; !ok {
+		return fmt.Errorf("binding of type '%s' is missing required entry '%s'", PipBindingType, PipConfigFile)
+	}
+
+	layer.BuildEnv.Override("PIP_CONFIG_FILE", filepath.Join(binding.Path, PipConfigFile))
+
+	if _, ok := binding.Entries[NetrcFile]; ok {
+		layer.BuildEnv.Override("NETRC", filepath.Join(binding.Path, NetrcFile))
+	}
+
+	return nil
+}
+
+// configureCABundle writes a CA certificate bundle containing the system CA
+// certificates, followed by the certificates provided by the given bindings
+// of type ca-certificates, into the layer and points invocations of pip in
+// downstream buildpacks at it.
+func configureCABundle(layer *packit.Layer, bindings []servicebindings.Binding) error {
+	bundle := bytes.NewBuffer(nil)
+
+	systemBundlePath, ok := os.LookupEnv("SSL_CERT_FILE")
+	if !ok {
+		systemBundlePath = SystemCABundle
+	}
+
+	systemBundle, err := os.ReadFile(systemBundlePath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read system CA certificates: %w", err)
+	}
+	bundle.Write(systemBundle)
+
+	for _, binding := range bindings {
+		var names []string
+		for name := range binding.Entries {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			certificate, err := binding.Entries[name].ReadBytes()
+			if err != nil {
+				return fmt.Errorf("failed to read certificate '%s' from binding '%s': %w", name, binding.Name, err)
+			}
+
+			if bundle.Len() > 0 && !bytes.HasSuffix(bundle.Bytes(), []byte("\n")) {
+				bundle.WriteString("\n")
+			}
+			bundle.Write(certificate)
+		}
+	}
+
+	bundlePath := filepath.Join(layer.Path, CABundleFile)
+	err = os.WriteFile(bundlePath, bundle.Bytes(), 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write CA certificate bundle: %w", err)
+	}
+
+	layer.BuildEnv.Override("PIP_CERT", bundlePath)
+
+	return nil
+}
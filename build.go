@@ -1,11 +1,16 @@
 package pip
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/dustin/go-humanize"
 	"github.com/paketo-buildpacks/packit/v2"
 	"github.com/paketo-buildpacks/packit/v2/cargo"
 	"github.com/paketo-buildpacks/packit/v2/chronos"
@@ -13,11 +18,15 @@ import (
 	"github.com/paketo-buildpacks/packit/v2/postal"
 	"github.com/paketo-buildpacks/packit/v2/sbom"
 	"github.com/paketo-buildpacks/packit/v2/scribe"
+	"github.com/paketo-buildpacks/packit/v2/servicebindings"
 )
 
 //go:generate faux --interface DependencyManager --output fakes/dependency_manager.go
 //go:generate faux --interface InstallProcess --output fakes/install_process.go
 //go:generate faux --interface SitePackageProcess --output fakes/site_package_process.go
+//go:generate faux --interface InterpreterProcess --output fakes/interpreter_process.go
+//go:generate faux --interface VersionProcess --output fakes/version_process.go
+//go:generate faux --interface BindingResolver --output fakes/binding_resolver.go
 //go:generate faux --interface SBOMGenerator --output fakes/sbom_generator.go
 
 // DependencyManager defines the interface for picking the best matching
@@ -28,9 +37,24 @@ type DependencyManager interface {
 	GenerateBillOfMaterials(dependencies ...postal.Dependency) []packit.BOMEntry
 }
 
+// InstallOptions configures how the pip dependency is installed.
+type InstallOptions struct {
+	// BreakSystemPackages allows pip to install into a layer for an externally
+	// managed (PEP 668) interpreter.
+	BreakSystemPackages bool
+
+	// Venv installs pip into a virtual environment in the layer rather than
+	// into its user base.
+	Venv bool
+
+	// FindLinks are directories of distributions to install from in addition
+	// to the source path, e.g. the build backend of a pip sdist.
+	FindLinks []string
+}
+
 // InstallProcess defines the interface for installing the pip dependency into a layer.
 type InstallProcess interface {
-	Execute(srcPath, targetLayerPath string) error
+	Execute(srcPath, targetLayerPath string, options InstallOptions) error
 }
 
 // SitePackageProcess defines the interface for looking site packages within a layer.
@@ -38,8 +62,25 @@ type SitePackageProcess interface {
 	Execute(targetLayerPath string) (string, error)
 }
 
+// InterpreterProcess defines the interface for inspecting the python interpreter.
+type InterpreterProcess interface {
+	Execute() (Interpreter, error)
+}
+
+// VersionProcess defines the interface for looking up the version of the pip installed in a layer.
+type VersionProcess interface {
+	Execute(targetLayerPath string) (string, error)
+}
+
+// BindingResolver defines the interface for looking up service bindings.
+type BindingResolver interface {
+	Resolve(typ, provider, platformDir string) ([]servicebindings.Binding, error)
+}
+
+// SBOMGenerator defines the interface for generating an SBOM that lists the
+// given dependencies.
 type SBOMGenerator interface {
-	GenerateFromDependency(dependency postal.Dependency, dir string) (sbom.SBOM, error)
+	GenerateFromDependencies(dependencies []postal.Dependency, dir string) (sbom.SBOM, error)
 }
 
 // Build will return a packit.BuildFunc that will be invoked during the build
@@ -52,6 +93,9 @@ func Build(
 	dependencies DependencyManager,
 	installProcess InstallProcess,
 	siteProcess SitePackageProcess,
+	interpreterProcess InterpreterProcess,
+	versionProcess VersionProcess,
+	bindingResolver BindingResolver,
 	sbomGenerator SBOMGenerator,
 	logger scribe.Emitter,
 	clock chronos.Clock,
@@ -67,13 +111,109 @@ func Build(
 
 		version, _ := entry.Metadata["version"].(string)
 
-		dependency, err := dependencies.Resolve(filepath.Join(context.CNBPath, "buildpack.toml"), entry.Name, version, context.Stack)
+		interpreter, err := interpreterProcess.Execute()
 		if err != nil {
 			return packit.BuildResult{}, err
 		}
 
-		dependency.Name = "Pip"
-		logger.SelectedDependency(entry, dependency, clock.Now())
+		installMode := os.Getenv("BP_PIP_INSTALL_MODE")
+		if installMode == "" {
+			installMode = UserInstallMode
+		}
+
+		if installMode != UserInstallMode && installMode != VenvInstallMode {
+			return packit.BuildResult{}, fmt.Errorf("unsupported BP_PIP_INSTALL_MODE %q: expected %q or %q", installMode, UserInstallMode, VenvInstallMode)
+		}
+
+		// pip refuses to install packages for an externally managed interpreter
+		// (PEP 668), even into a user base. Since pip and the packages of
+		// downstream buildpacks are installed into layers rather than into the
+		// system, pip is allowed to proceed during the build only. A virtual
+		// environment is never externally managed.
+		breakSystemPackages := interpreter.ExternallyManaged() && installMode == UserInstallMode
+		if interpreter.ExternallyManaged() {
+			logger.Process("Python %s is externally managed (PEP 668)", interpreter.Version)
+			if breakSystemPackages {
+				logger.Subprocess("Strategy: setting PIP_BREAK_SYSTEM_PACKAGES for the pip installation and in the build environment only")
+			} else {
+				logger.Subprocess("Strategy: installing pip into a virtual environment")
+			}
+			logger.Break()
+		}
+
+		adoptSystemPip := false
+		if value, ok := os.LookupEnv("BP_PIP_ADOPT_SYSTEM"); ok {
+			adoptSystemPip, err = strconv.ParseBool(value)
+			if err != nil {
+				return packit.BuildResult{}, fmt.Errorf("failed to parse BP_PIP_ADOPT_SYSTEM value %q: %w", value, err)
+			}
+		}
+
+		// Requesting the "system" version adopts any pip bundled with the
+		// interpreter, and falls back to the default version of pip otherwise.
+		if version == SystemVersion {
+			adoptSystemPip, version = true, ""
+		}
+
+		var dependency postal.Dependency
+		pipSource := os.Getenv("BP_PIP_SOURCE")
+		if pipSource != "" && !filepath.IsAbs(pipSource) {
+			pipSource = filepath.Join(context.WorkingDir, pipSource)
+		}
+
+		adopted := false
+		if pipSource != "" {
+			logger.Process("Using pip from BP_PIP_SOURCE %s", pipSource)
+			dependency, err = sourceDependency(pipSource, os.Getenv("BP_PIP_SOURCE_SHA256"))
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+			logger.Break()
+		} else if adoptSystemPip {
+			logger.Process("Checking the pip bundled with Python %s", interpreter.Version)
+			systemVersion, err := versionProcess.Execute("")
+			switch {
+			case err != nil:
+				logger.Subprocess("No pip is bundled with the interpreter")
+			case !systemPipSatisfies(systemVersion, version):
+				logger.Subprocess("System pip %s does not satisfy %s", systemVersion, version)
+			default:
+				dependency, adopted = pipDependency(systemVersion), true
+			}
+			logger.Break()
+		}
+
+		if !adopted && pipSource == "" {
+			buildpackTOMLPath := filepath.Join(context.CNBPath, "buildpack.toml")
+			source, ok := entry.Metadata["version-source"].(string)
+			if !ok {
+				source = "<unknown>"
+			}
+
+			requested := version
+			version, err = compatibleVersion(logger, buildpackTOMLPath, entry.Name, version, source, context.Stack, interpreter.Version)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			dependency, err = dependencies.Resolve(buildpackTOMLPath, entry.Name, version, context.Stack)
+			if err != nil {
+				return packit.BuildResult{}, resolutionError(buildpackTOMLPath, entry.Name, requested, source, context.Stack, interpreter.Version, err)
+			}
+
+			dependency.Name = "Pip"
+		}
+
+		// The deprecation of the dependency is reported by checkDeprecation,
+		// with a configurable window, rather than by the emitter.
+		selected := dependency
+		selected.DeprecationDate = time.Time{}
+		logger.SelectedDependency(entry, selected, clock.Now())
+
+		err = checkDeprecation(logger, dependency, clock.Now())
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
 
 		legacySBOM := dependencies.GenerateBillOfMaterials(dependency)
 		launch, build := planner.MergeLayerTypes(Pip, context.Plan.Entries)
@@ -88,30 +228,245 @@ func Build(
 			buildMetadata.BOM = legacySBOM
 		}
 
+		pipBindings, err := bindingResolver.Resolve(PipBindingType, "", context.Platform.Path)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		if len(pipBindings) > 1 {
+			return packit.BuildResult{}, fmt.Errorf("binding resolver found more than one binding of type '%s'", PipBindingType)
+		}
+
+		caBindings, err := bindingResolver.Resolve(CACertificatesBindingType, "", context.Platform.Path)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		// Known vulnerabilities are looked up in advisories provided with the
+		// build rather than online, so that the check also works offline.
+		advisoryBindings, err := bindingResolver.Resolve(AdvisoriesBindingType, "", context.Platform.Path)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		advisories, err := loadAdvisories(advisoryBindings, context.WorkingDir)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		vulnPolicy, err := vulnerabilityPolicy()
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		err = checkAdvisories(logger, advisories, vulnPolicy, []postal.Dependency{dependency})
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		var additionalLayers []packit.Layer
+		if len(pipBindings) == 1 || len(caBindings) > 0 || (breakSystemPackages && build) {
+			pipConfigLayer, err := context.Layers.Get(PipConfig)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			pipConfigLayer, err = pipConfigLayer.Reset()
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			// The configuration refers to the bindings, which may change between
+			// builds, so the layer is never cached or available at launch.
+			pipConfigLayer.Launch, pipConfigLayer.Build, pipConfigLayer.Cache = false, build, false
+
+			if len(pipBindings) == 1 {
+				logger.Process("Configuring pip from binding '%s'", pipBindings[0].Name)
+				err = configurePipBinding(&pipConfigLayer, pipBindings[0])
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+			}
+
+			if len(caBindings) > 0 {
+				logger.Process("Adding CA certificates from %d binding(s) of type '%s'", len(caBindings), CACertificatesBindingType)
+				err = configureCABundle(&pipConfigLayer, caBindings)
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+			}
+
+			if breakSystemPackages {
+				pipConfigLayer.BuildEnv.Override("PIP_BREAK_SYSTEM_PACKAGES", "1")
+			}
+
+			logger.EnvironmentVariables(pipConfigLayer)
+
+			additionalLayers = append(additionalLayers, pipConfigLayer)
+		}
+
+		if build {
+			pipCacheLayer, err := context.Layers.Get(PipCache)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			err = os.MkdirAll(pipCacheLayer.Path, os.ModePerm)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			maxSize, err := cacheMaxSize()
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			size, prunedSize, err := pruneCache(pipCacheLayer.Path, maxSize)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			logger.Process("Configuring pip cache")
+			logger.Subprocess("Using %s of %s", humanize.IBytes(prunedSize), humanize.IBytes(maxSize))
+			if prunedSize != size {
+				logger.Action("Pruned from %s", humanize.IBytes(size))
+			}
+			logger.Break()
+
+			// The cache is only useful to downstream buildpacks, so it is never
+			// exported at launch.
+			pipCacheLayer.Launch, pipCacheLayer.Build, pipCacheLayer.Cache = false, true, true
+			pipCacheLayer.BuildEnv.Override("PIP_CACHE_DIR", pipCacheLayer.Path)
+
+			logger.EnvironmentVariables(pipCacheLayer)
+
+			additionalLayers = append(additionalLayers, pipCacheLayer)
+		}
+
 		pipLayer, err := context.Layers.Get(Pip)
 		if err != nil {
 			return packit.BuildResult{}, err
 		}
 
+		if adopted {
+			logger.Process("System pip %s adopted, skipping installation", dependency.Version)
+			logger.Break()
+
+			// The pip layer only carries the SBOM of the system pip, which is
+			// already on the path of the interpreter.
+			pipLayer, err = pipLayer.Reset()
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			pipLayer.Launch, pipLayer.Build, pipLayer.Cache = launch, build, false
+
+			logger.GeneratingSBOM(pipLayer.Path)
+			var sbomContent sbom.SBOM
+			duration, err := clock.Measure(func() error {
+				sbomContent, err = sbomGenerator.GenerateFromDependencies([]postal.Dependency{dependency}, pipLayer.Path)
+				return err
+			})
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			logger.Action("Completed in %s", duration.Round(time.Millisecond))
+			logger.Break()
+
+			logger.FormattingSBOM(context.BuildpackInfo.SBOMFormats...)
+			pipLayer.SBOM, err = sbomContent.InFormats(context.BuildpackInfo.SBOMFormats...)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			pipLayer.Metadata = map[string]interface{}{
+				SystemPipVersionKey: dependency.Version,
+			}
+
+			return packit.BuildResult{
+				Layers: append([]packit.Layer{pipLayer}, additionalLayers...),
+				Build:  buildMetadata,
+				Launch: launchMetadata,
+			}, nil
+		}
+
 		pipSrcLayer, err := context.Layers.Get(PipSrc)
 		if err != nil {
 			return packit.BuildResult{}, err
 		}
 
-		cachedChecksum, ok := pipLayer.Metadata[DependencyChecksumKey].(string)
-		if ok && cargo.Checksum(cachedChecksum).Match(cargo.Checksum(dependency.Checksum)) {
+		extraFiles, extraDigest, err := extraFindLinks(context.WorkingDir)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		layerMetadata := map[string]interface{}{
+			DependencyChecksumKey: dependency.Checksum,
+			PythonVersionKey:      interpreter.Version,
+			PythonABIKey:          interpreter.ABI,
+			ArchitectureKey:       runtime.GOARCH,
+			InstallModeKey:        installMode,
+		}
+
+		if extraDigest != "" {
+			layerMetadata[ExtraFindLinksKey] = extraDigest
+		}
+
+		disableConstraints := false
+		if value, ok := os.LookupEnv("BP_PIP_DISABLE_CONSTRAINTS"); ok {
+			disableConstraints, err = strconv.ParseBool(value)
+			if err != nil {
+				return packit.BuildResult{}, fmt.Errorf("failed to parse BP_PIP_DISABLE_CONSTRAINTS value %q: %w", value, err)
+			}
+		}
+
+		if disableConstraints {
+			layerMetadata[ConstraintsKey] = "disabled"
+		}
+
+		// The site packages directory recorded on a previous build is reused
+		// rather than looked up again, as long as it is still in the layer.
+		sitePackages, _ := pipLayer.Metadata[SitePackagesKey].(string)
+
+		rebuildReason := layerRebuildReason(pipLayer.Metadata, layerMetadata)
+		if rebuildReason == "" && installMode == UserInstallMode {
+			rebuildReason = sitePackagesRebuildReason(pipLayer.Path, sitePackages)
+		}
+
+		if rebuildReason == "" {
 			logger.Process("Reusing cached layer %s", pipLayer.Path)
 			logger.Process("Reusing cached layer %s", pipSrcLayer.Path)
 			pipLayer.Launch, pipLayer.Build, pipLayer.Cache = launch, build, build
 			pipSrcLayer.Launch, pipSrcLayer.Build, pipSrcLayer.Cache = false, build, build
 
+			if installMode == UserInstallMode {
+				pipLayer.SharedEnv.Prepend("PYTHONPATH", sitePackagesPath(pipLayer.Path, sitePackages), ":")
+			}
+
+			if len(advisories) > 0 {
+				bundled, err := bundledDependencies(pipSrcLayer.Path)
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+
+				err = checkAdvisories(logger, advisories, vulnPolicy, bundled)
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+			}
+
 			return packit.BuildResult{
-				Layers: []packit.Layer{pipLayer, pipSrcLayer},
+				Layers: append([]packit.Layer{pipLayer, pipSrcLayer}, additionalLayers...),
 				Build:  buildMetadata,
 				Launch: launchMetadata,
 			}, nil
 		}
 
+		if len(pipLayer.Metadata) > 0 {
+			logger.Process("Rebuilding cached layer %s: %s", pipLayer.Path, rebuildReason)
+		}
+
 		pipLayer, err = pipLayer.Reset()
 		if err != nil {
 			return packit.BuildResult{}, err
@@ -131,11 +486,41 @@ func Build(
 		logger.Subprocess(fmt.Sprintf("Installing Pip %s", dependency.Version))
 
 		duration, err := clock.Measure(func() error {
-			err = dependencies.Deliver(dependency, context.CNBPath, pipSrcLayer.Path, context.Platform.Path)
+			if pipSource != "" {
+				err = deliverSource(pipSource, pipSrcLayer.Path)
+			} else {
+				err = dependencies.Deliver(dependency, context.CNBPath, pipSrcLayer.Path, context.Platform.Path)
+			}
 			if err != nil {
 				return err
 			}
-			return installProcess.Execute(pipSrcLayer.Path, pipLayer.Path)
+
+			var findLinks []string
+			if len(extraFiles) > 0 {
+				err = deliverExtraFindLinks(extraFiles, filepath.Join(pipSrcLayer.Path, ExtraFindLinksDir))
+				if err != nil {
+					return err
+				}
+				findLinks = append(findLinks, filepath.Join(pipSrcLayer.Path, ExtraFindLinksDir))
+			}
+
+			err = installProcess.Execute(pipSrcLayer.Path, pipLayer.Path, InstallOptions{
+				BreakSystemPackages: breakSystemPackages,
+				Venv:                installMode == VenvInstallMode,
+				FindLinks:           findLinks,
+			})
+			if err != nil {
+				var installErr InstallError
+				if errors.As(err, &installErr) {
+					logger.Action("Failed to install pip: %s", installErr.Cause)
+					logger.Detail("%s", installErr.Remediation)
+					logger.Break()
+				}
+
+				return err
+			}
+
+			return verifyInstallation(versionProcess, dependency, pipLayer.Path)
 		})
 		if err != nil {
 			return packit.BuildResult{}, err
@@ -144,52 +529,203 @@ func Build(
 		logger.Action("Completed in %s", duration.Round(time.Millisecond))
 		logger.Break()
 
+		// The SBOM of both layers lists pip alongside the distributions bundled
+		// with it in the pip-source layer (setuptools, wheel, etc.).
+		bundled, err := bundledDependencies(pipSrcLayer.Path)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		err = checkAdvisories(logger, advisories, vulnPolicy, bundled)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		var extra []postal.Dependency
+		if len(extraFiles) > 0 {
+			extra, err = bundledDependencies(filepath.Join(pipSrcLayer.Path, ExtraFindLinksDir))
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+		}
+
+		sbomDependencies := append(append([]postal.Dependency{dependency}, bundled...), extra...)
+
 		logger.GeneratingSBOM(pipLayer.Path)
-		var sbomContent sbom.SBOM
+		var pipSBOM, pipSrcSBOM sbom.SBOM
 		duration, err = clock.Measure(func() error {
-			sbomContent, err = sbomGenerator.GenerateFromDependency(dependency, pipLayer.Path)
+			pipSBOM, err = sbomGenerator.GenerateFromDependencies(sbomDependencies, pipLayer.Path)
+			if err != nil {
+				return err
+			}
+
+			pipSrcSBOM, err = sbomGenerator.GenerateFromDependencies(sbomDependencies, pipSrcLayer.Path)
 			return err
 		})
 		if err != nil {
 			return packit.BuildResult{}, err
 		}
 
+		for _, bundledDependency := range sbomDependencies[1:] {
+			logger.Subprocess("Bundled %s %s", bundledDependency.Name, bundledDependency.Version)
+		}
 		logger.Action("Completed in %s", duration.Round(time.Millisecond))
 		logger.Break()
 
 		logger.FormattingSBOM(context.BuildpackInfo.SBOMFormats...)
-		pipLayer.SBOM, err = sbomContent.InFormats(context.BuildpackInfo.SBOMFormats...)
+		pipLayer.SBOM, err = pipSBOM.InFormats(context.BuildpackInfo.SBOMFormats...)
 		if err != nil {
 			return packit.BuildResult{}, err
 		}
 
-		// Look up the site packages path and prepend it onto $PYTHONPATH
-		sitePackagesPath, err := siteProcess.Execute(pipLayer.Path)
+		pipSrcLayer.SBOM, err = pipSrcSBOM.InFormats(context.BuildpackInfo.SBOMFormats...)
 		if err != nil {
-			return packit.BuildResult{}, fmt.Errorf("failed to locate site packages in pip layer: %w", err)
+			return packit.BuildResult{}, err
 		}
-		if sitePackagesPath == "" {
-			return packit.BuildResult{}, fmt.Errorf("pip installation failed: site packages are missing from the pip layer")
+
+		if installMode == VenvInstallMode {
+			// Activate the virtual environment by putting its python ahead of the
+			// python of the interpreter on the $PATH.
+			pipLayer.SharedEnv.Override("VIRTUAL_ENV", pipLayer.Path)
+			pipLayer.SharedEnv.Prepend("PATH", filepath.Join(pipLayer.Path, "bin"), ":")
+		} else {
+			// Look up the site packages path and prepend it onto $PYTHONPATH
+			path, err := siteProcess.Execute(pipLayer.Path)
+			if err != nil {
+				return packit.BuildResult{}, fmt.Errorf("failed to locate site packages in pip layer: %w", err)
+			}
+			if path == "" {
+				return packit.BuildResult{}, fmt.Errorf("pip installation failed: site packages are missing from the pip layer")
+			}
+			path = strings.TrimRight(path, "\n")
+			pipLayer.SharedEnv.Prepend("PYTHONPATH", path, ":")
+
+			// Record the path relative to the layer, so that it remains valid
+			// when the layers directory moves.
+			if relative, err := filepath.Rel(pipLayer.Path, path); err == nil && !strings.HasPrefix(relative, "..") {
+				path = relative
+			}
+			layerMetadata[SitePackagesKey] = path
 		}
-		pipLayer.SharedEnv.Prepend("PYTHONPATH", strings.TrimRight(sitePackagesPath, "\n"), ":")
 
 		// Append the pip source layer path to PIP_FIND_LINKS so that invocations
 		// of pip in downstream buildpacks have access to the packages bundled with
 		// the pip dependency (setuptools, wheel, etc.).
 
-		pipSrcLayer.BuildEnv.Append("PIP_FIND_LINKS", strings.TrimRight(pipSrcLayer.Path, "\n"), " ")
+		findLinks := []string{strings.TrimRight(pipSrcLayer.Path, "\n")}
+		if len(extraFiles) > 0 {
+			findLinks = append(findLinks, filepath.Join(pipSrcLayer.Path, ExtraFindLinksDir))
+		}
+		pipSrcLayer.BuildEnv.Append("PIP_FIND_LINKS", strings.Join(findLinks, " "), " ")
+
+		// Pin the build tooling of downstream invocations of pip to the versions
+		// bundled in the pip source layer.
+		if !disableConstraints && len(bundled) > 0 {
+			constraintsPath := filepath.Join(pipSrcLayer.Path, ConstraintsFile)
+			err = writeConstraints(constraintsPath, bundled)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			pipSrcLayer.BuildEnv.Append("PIP_CONSTRAINT", constraintsPath, " ")
+		}
 
 		logger.EnvironmentVariables(pipSrcLayer)
 		logger.EnvironmentVariables(pipLayer)
 
-		pipLayer.Metadata = map[string]interface{}{
-			DependencyChecksumKey: dependency.Checksum,
-		}
+		pipLayer.Metadata = layerMetadata
 
 		return packit.BuildResult{
-			Layers: []packit.Layer{pipLayer, pipSrcLayer},
+			Layers: append([]packit.Layer{pipLayer, pipSrcLayer}, additionalLayers...),
 			Build:  buildMetadata,
 			Launch: launchMetadata,
 		}, nil
 	}
 }
+
+// sitePackagesRebuildReason describes why the site packages directory
+// recorded in the metadata of a cached pip layer cannot be reused. It returns
+// an empty string when the directory is still present in the layer.
+func sitePackagesRebuildReason(layerPath, sitePackages string) string {
+	if sitePackages == "" {
+		return "site packages directory was not recorded"
+	}
+
+	_, err := os.Stat(sitePackagesPath(layerPath, sitePackages))
+	if err != nil {
+		return fmt.Sprintf("site packages directory %s is missing", sitePackages)
+	}
+
+	return ""
+}
+
+// sitePackagesPath resolves the site packages directory recorded in the
+// metadata of the pip layer at the given path.
+func sitePackagesPath(layerPath, sitePackages string) string {
+	if filepath.IsAbs(sitePackages) {
+		return sitePackages
+	}
+
+	return filepath.Join(layerPath, sitePackages)
+}
+
+// layerRebuildReason compares the metadata of a cached pip layer with the
+// metadata of the layer that would be built, and describes the first
+// difference that prevents the cached layer from being reused. It returns an
+// empty string when the cached layer can be reused.
+func layerRebuildReason(cached, expected map[string]interface{}) string {
+	cachedChecksum, _ := cached[DependencyChecksumKey].(string)
+	expectedChecksum, _ := expected[DependencyChecksumKey].(string)
+	if cachedChecksum == "" || !cargo.Checksum(cachedChecksum).Match(cargo.Checksum(expectedChecksum)) {
+		return "pip dependency checksum changed"
+	}
+
+	for _, key := range []struct {
+		name        string
+		description string
+		missing     string
+	}{
+		{PythonVersionKey, "Python version", "<unknown>"},
+		{PythonABIKey, "Python ABI", "<unknown>"},
+		{ArchitectureKey, "architecture", "<unknown>"},
+		{InstallModeKey, "install mode", "<unknown>"},
+		{ExtraFindLinksKey, "BP_PIP_EXTRA_FIND_LINKS digest", "<none>"},
+		{ConstraintsKey, "bundled constraints", "enabled"},
+	} {
+		cachedValue, _ := cached[key.name].(string)
+		expectedValue, _ := expected[key.name].(string)
+		if cachedValue != expectedValue {
+			if cachedValue == "" {
+				cachedValue = key.missing
+			}
+			if expectedValue == "" {
+				expectedValue = key.missing
+			}
+			return fmt.Sprintf("%s changed from %s to %s", key.description, cachedValue, expectedValue)
+		}
+	}
+
+	return ""
+}
+
+// verifyInstallation runs the pip installed in the layer at the given path
+// and checks that it reports the version of the given dependency.
+func verifyInstallation(versionProcess VersionProcess, dependency postal.Dependency, layerPath string) error {
+	installedVersion, err := versionProcess.Execute(layerPath)
+	if err != nil {
+		return fmt.Errorf("failed to verify pip installation: %w", err)
+	}
+
+	// The versions are compared as PEP 440 versions (e.g. 24.1 and 24.1.0 are
+	// equal), or exactly when they cannot be translated, e.g. post-releases.
+	if installedVersion == dependency.Version {
+		return nil
+	}
+
+	comparison, err := compareVersions(installedVersion, dependency.Version)
+	if err != nil || comparison != 0 {
+		return fmt.Errorf("failed to verify pip installation: expected pip %s but the installed pip reports %s", dependency.Version, installedVersion)
+	}
+
+	return nil
+}
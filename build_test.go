@@ -1,12 +1,18 @@
 package pip_test
 
 import (
+	"archive/tar"
+	"archive/zip"
 	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"testing"
+	"time"
 
 	"github.com/paketo-buildpacks/packit/v2"
 	"github.com/paketo-buildpacks/packit/v2/chronos"
@@ -16,11 +22,13 @@ import (
 	"github.com/paketo-buildpacks/packit/v2/postal"
 	"github.com/paketo-buildpacks/packit/v2/sbom"
 	"github.com/paketo-buildpacks/packit/v2/scribe"
+	"github.com/paketo-buildpacks/packit/v2/servicebindings"
 	"github.com/paketo-buildpacks/pip"
 	"github.com/paketo-buildpacks/pip/fakes"
 	"github.com/sclevine/spec"
 
 	. "github.com/onsi/gomega"
+	. "github.com/paketo-buildpacks/packit/v2/matchers"
 )
 
 func testBuild(t *testing.T, context spec.G, it spec.S) {
@@ -33,6 +41,11 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 		dependencyManager  *fakes.DependencyManager
 		installProcess     *fakes.InstallProcess
 		sitePackageProcess *fakes.SitePackageProcess
+		interpreterProcess *fakes.InterpreterProcess
+		versionProcess     *fakes.VersionProcess
+		bindingResolver    *fakes.BindingResolver
+		bindings           map[string][]servicebindings.Binding
+		resolvedTypes      []string
 		sbomGenerator      *fakes.SBOMGenerator
 
 		logEmitter scribe.Emitter
@@ -51,6 +64,13 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 		cnbDir, err = os.MkdirTemp("", "cnb")
 		Expect(err).NotTo(HaveOccurred())
 
+		Expect(os.WriteFile(filepath.Join(cnbDir, "buildpack.toml"), []byte(`
+[[metadata.dependencies]]
+  id = "pip"
+  stacks = ["*"]
+  version = "21.0.0"
+`), 0600)).To(Succeed())
+
 		dependencyManager = &fakes.DependencyManager{}
 		dependencyManager.ResolveCall.Returns.Dependency = postal.Dependency{
 			ID:       "pip",
@@ -77,7 +97,7 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 		}
 
 		installProcess = &fakes.InstallProcess{}
-		installProcess.ExecuteCall.Stub = func(srcPath, targetLayerPath string) error {
+		installProcess.ExecuteCall.Stub = func(srcPath, targetLayerPath string, options pip.InstallOptions) error {
 			err = os.MkdirAll(filepath.Join(layersDir, "pip", "lib", "python1.23", "site-packages"), os.ModePerm)
 			if err != nil {
 				return fmt.Errorf("issue with stub call: %s", err)
@@ -88,9 +108,26 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 		sitePackageProcess = &fakes.SitePackageProcess{}
 		sitePackageProcess.ExecuteCall.Returns.String = filepath.Join(layersDir, "pip", "lib", "python1.23", "site-packages")
 
+		interpreterProcess = &fakes.InterpreterProcess{}
+		interpreterProcess.ExecuteCall.Returns.Interpreter = pip.Interpreter{
+			Version: "1.23.4",
+			ABI:     "cpython-123-x86_64-linux-gnu",
+		}
+
+		versionProcess = &fakes.VersionProcess{}
+		versionProcess.ExecuteCall.Returns.String = "21.0"
+
+		bindings = map[string][]servicebindings.Binding{}
+		resolvedTypes = nil
+		bindingResolver = &fakes.BindingResolver{}
+		bindingResolver.ResolveCall.Stub = func(typ, provider, platformDir string) ([]servicebindings.Binding, error) {
+			resolvedTypes = append(resolvedTypes, typ)
+			return bindings[typ], nil
+		}
+
 		// Syft SBOM
 		sbomGenerator = &fakes.SBOMGenerator{}
-		sbomGenerator.GenerateFromDependencyCall.Returns.SBOM = sbom.SBOM{}
+		sbomGenerator.GenerateFromDependenciesCall.Returns.SBOM = sbom.SBOM{}
 
 		buffer = bytes.NewBuffer(nil)
 		logEmitter = scribe.NewEmitter(buffer)
@@ -99,6 +136,9 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 			dependencyManager,
 			installProcess,
 			sitePackageProcess,
+			interpreterProcess,
+			versionProcess,
+			bindingResolver,
 			sbomGenerator,
 			logEmitter,
 			chronos.DefaultClock,
@@ -148,8 +188,14 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 		Expect(pipLayer.Launch).To(BeFalse())
 		Expect(pipLayer.Cache).To(BeFalse())
 
-		Expect(pipLayer.Metadata).To(HaveLen(1))
-		Expect(pipLayer.Metadata["dependency_checksum"]).To(Equal("some-sha"))
+		Expect(pipLayer.Metadata).To(Equal(map[string]interface{}{
+			"dependency_checksum": "some-sha",
+			"python_version":      "1.23.4",
+			"python_abi":          "cpython-123-x86_64-linux-gnu",
+			"arch":                runtime.GOARCH,
+			"install_mode":        "user",
+			"site_packages":       "lib/python1.23/site-packages",
+		}))
 
 		Expect(pipLayer.SharedEnv).To(HaveLen(2))
 		Expect(pipLayer.SharedEnv["PYTHONPATH.delim"]).To(Equal(":"))
@@ -179,6 +225,8 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 		Expect(pipSrcLayer.BuildEnv["PIP_FIND_LINKS.delim"]).To(Equal(" "))
 		Expect(pipSrcLayer.BuildEnv["PIP_FIND_LINKS.append"]).To(Equal(filepath.Join(layersDir, "pip-source")))
 
+		Expect(pipSrcLayer.SBOM.Formats()).To(HaveLen(2))
+
 		Expect(dependencyManager.ResolveCall.Receives.Path).To(Equal(filepath.Join(cnbDir, "buildpack.toml")))
 		Expect(dependencyManager.ResolveCall.Receives.Id).To(Equal("pip"))
 		Expect(dependencyManager.ResolveCall.Receives.Version).To(Equal(""))
@@ -197,24 +245,37 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 		Expect(dependencyManager.DeliverCall.Receives.DestinationPath).To(ContainSubstring("pip-source"))
 		Expect(dependencyManager.DeliverCall.Receives.PlatformPath).To(Equal("platform"))
 
-		Expect(sbomGenerator.GenerateFromDependencyCall.Receives.Dir).To(Equal(filepath.Join(layersDir, "pip")))
+		Expect(sbomGenerator.GenerateFromDependenciesCall.CallCount).To(Equal(2))
+		Expect(sbomGenerator.GenerateFromDependenciesCall.Receives.Dependencies).To(Equal([]postal.Dependency{dependencyManager.ResolveCall.Returns.Dependency}))
+		Expect(sbomGenerator.GenerateFromDependenciesCall.Receives.Dir).To(Equal(filepath.Join(layersDir, "pip-source")))
+
+		Expect(resolvedTypes).To(Equal([]string{"pip", "ca-certificates", "pip-advisories"}))
+		Expect(bindingResolver.ResolveCall.Receives.Provider).To(Equal(""))
+		Expect(bindingResolver.ResolveCall.Receives.PlatformDir).To(Equal("platform"))
 
 		Expect(installProcess.ExecuteCall.Receives.SrcPath).To(Equal(dependencyManager.DeliverCall.Receives.DestinationPath))
 		Expect(installProcess.ExecuteCall.Receives.TargetLayerPath).To(Equal(filepath.Join(layersDir, "pip")))
+		Expect(installProcess.ExecuteCall.Receives.Options).To(Equal(pip.InstallOptions{}))
+		Expect(sitePackageProcess.ExecuteCall.Receives.TargetLayerPath).To(Equal(filepath.Join(layersDir, "pip")))
+
+		Expect(versionProcess.ExecuteCall.Receives.TargetLayerPath).To(Equal(filepath.Join(layersDir, "pip")))
 
 		Expect(buffer.String()).To(ContainSubstring("Some Buildpack some-version"))
 		Expect(buffer.String()).To(ContainSubstring("Executing build process"))
 		Expect(buffer.String()).To(ContainSubstring("Installing Pip"))
 	})
 
-	context("when build plan entries require pip at build/launch", func() {
+	context("when pip is requested at launch by BP_PIP_LAUNCH", func() {
 		it.Before(func() {
-			buildContext.Plan.Entries[0].Metadata = make(map[string]interface{})
-			buildContext.Plan.Entries[0].Metadata["build"] = true
-			buildContext.Plan.Entries[0].Metadata["launch"] = true
+			buildContext.Plan.Entries = append(buildContext.Plan.Entries, packit.BuildpackPlanEntry{
+				Name: "pip",
+				Metadata: map[string]interface{}{
+					"launch": true,
+				},
+			})
 		})
 
-		it("makes the layer available at the right times", func() {
+		it("exports the pip layer at launch and keeps the pip-source layer build-only", func() {
 			result, err := build(buildContext)
 			Expect(err).NotTo(HaveOccurred())
 
@@ -222,193 +283,2005 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 			pipLayer := result.Layers[0]
 
 			Expect(pipLayer.Name).To(Equal("pip"))
-
-			Expect(pipLayer.Build).To(BeTrue())
 			Expect(pipLayer.Launch).To(BeTrue())
-			Expect(pipLayer.Cache).To(BeTrue())
+			Expect(pipLayer.Build).To(BeFalse())
+			Expect(pipLayer.SharedEnv["PYTHONPATH.prepend"]).To(Equal(filepath.Join(layersDir, "pip", "lib/python1.23/site-packages")))
 
 			pipSrcLayer := result.Layers[1]
 
 			Expect(pipSrcLayer.Name).To(Equal("pip-source"))
-
-			Expect(pipSrcLayer.Build).To(BeTrue())
 			Expect(pipSrcLayer.Launch).To(BeFalse())
-			Expect(pipSrcLayer.Cache).To(BeTrue())
-
-			Expect(result.Build.BOM).To(Equal(
-				[]packit.BOMEntry{
-					{
-						Name: "pip",
-						Metadata: paketosbom.BOMMetadata{
-							Checksum: paketosbom.BOMChecksum{
-								Algorithm: paketosbom.SHA256,
-								Hash:      "pip-dependency-sha",
-							},
-							URI:     "pip-dependency-uri",
-							Version: "pip-dependency-version",
-						},
-					},
-				},
-			))
+			Expect(pipSrcLayer.LaunchEnv).To(BeEmpty())
+			Expect(pipSrcLayer.SharedEnv).To(BeEmpty())
 
-			Expect(result.Launch.BOM).To(Equal(
-				[]packit.BOMEntry{
-					{
-						Name: "pip",
-						Metadata: paketosbom.BOMMetadata{
-							Checksum: paketosbom.BOMChecksum{
-								Algorithm: paketosbom.SHA256,
-								Hash:      "pip-dependency-sha",
-							},
-							URI:     "pip-dependency-uri",
-							Version: "pip-dependency-version",
-						},
-					},
-				},
-			))
+			Expect(result.Launch.BOM).To(HaveLen(1))
+			Expect(result.Build.BOM).To(BeEmpty())
 		})
 	})
 
-	context("when rebuilding a layer", func() {
+	context("when the python interpreter is externally managed", func() {
+		var stdlibDir string
+
 		it.Before(func() {
-			err := os.WriteFile(filepath.Join(layersDir, fmt.Sprintf("%s.toml", pip.Pip)), []byte(fmt.Sprintf(`[metadata]
-			%s = "some-sha"
-			built_at = "some-build-time"
-			`, pip.DependencyChecksumKey)), os.ModePerm)
+			var err error
+			stdlibDir, err = os.MkdirTemp("", "stdlib")
 			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(stdlibDir, "EXTERNALLY-MANAGED"), []byte("[externally-managed]"), 0600)).To(Succeed())
 
-			buildContext.Plan.Entries[0].Metadata = make(map[string]interface{})
-			buildContext.Plan.Entries[0].Metadata["build"] = true
-			buildContext.Plan.Entries[0].Metadata["launch"] = false
+			interpreterProcess.ExecuteCall.Returns.Interpreter.Stdlib = stdlibDir
+
+			buildContext.Plan.Entries[0].Metadata = map[string]interface{}{
+				"build": true,
+			}
 		})
 
-		it("skips the build process if the cached dependency sha matches the selected dependency sha", func() {
+		it.After(func() {
+			Expect(os.RemoveAll(stdlibDir)).To(Succeed())
+		})
+
+		it("allows pip to break system packages during the build only", func() {
 			result, err := build(buildContext)
 			Expect(err).NotTo(HaveOccurred())
 
-			Expect(result.Layers).To(HaveLen(2))
-			pipLayer := result.Layers[0]
+			Expect(installProcess.ExecuteCall.Receives.Options).To(Equal(pip.InstallOptions{BreakSystemPackages: true}))
+
+			Expect(result.Layers).To(HaveLen(4))
+			pipConfigLayer := result.Layers[2]
+
+			Expect(pipConfigLayer.Name).To(Equal("pip-config"))
+			Expect(pipConfigLayer.Build).To(BeTrue())
+			Expect(pipConfigLayer.Launch).To(BeFalse())
+			Expect(pipConfigLayer.Cache).To(BeFalse())
+			Expect(pipConfigLayer.BuildEnv).To(Equal(packit.Environment{
+				"PIP_BREAK_SYSTEM_PACKAGES.override": "1",
+			}))
+
+			Expect(result.Layers[0].SharedEnv).NotTo(HaveKey("PIP_BREAK_SYSTEM_PACKAGES.override"))
+
+			Expect(buffer.String()).To(ContainSubstring("Python 1.23.4 is externally managed (PEP 668)"))
+			Expect(buffer.String()).To(ContainSubstring("Strategy: setting PIP_BREAK_SYSTEM_PACKAGES for the pip installation and in the build environment only"))
+		})
+
+		context("when pip is not required at build time", func() {
+			it.Before(func() {
+				buildContext.Plan.Entries[0].Metadata = map[string]interface{}{
+					"launch": true,
+				}
+			})
+
+			it("does not configure the build environment", func() {
+				result, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(installProcess.ExecuteCall.Receives.Options).To(Equal(pip.InstallOptions{BreakSystemPackages: true}))
+				Expect(result.Layers).To(HaveLen(2))
+			})
+		})
+	})
+
+	context("when BP_PIP_INSTALL_MODE is venv", func() {
+		it.Before(func() {
+			t.Setenv("BP_PIP_INSTALL_MODE", "venv")
+
+			buildContext.Plan.Entries[0].Metadata = map[string]interface{}{
+				"build":  true,
+				"launch": true,
+			}
+		})
 
+		it("installs pip into a virtual environment and activates it", func() {
+			result, err := build(buildContext)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(installProcess.ExecuteCall.Receives.Options).To(Equal(pip.InstallOptions{Venv: true}))
+			Expect(sitePackageProcess.ExecuteCall.CallCount).To(Equal(0))
+
+			pipLayer := result.Layers[0]
 			Expect(pipLayer.Name).To(Equal("pip"))
+			Expect(pipLayer.SharedEnv).To(Equal(packit.Environment{
+				"VIRTUAL_ENV.override": filepath.Join(layersDir, "pip"),
+				"PATH.prepend":         filepath.Join(layersDir, "pip", "bin"),
+				"PATH.delim":           ":",
+			}))
+			Expect(pipLayer.Metadata["install_mode"]).To(Equal("venv"))
+		})
 
-			Expect(pipLayer.Build).To(BeTrue())
-			Expect(pipLayer.Launch).To(BeFalse())
-			Expect(pipLayer.Cache).To(BeTrue())
+		context("when the python interpreter is externally managed", func() {
+			it.Before(func() {
+				stdlibDir := t.TempDir()
+				Expect(os.WriteFile(filepath.Join(stdlibDir, "EXTERNALLY-MANAGED"), nil, 0600)).To(Succeed())
 
-			pipSrcLayer := result.Layers[1]
+				interpreterProcess.ExecuteCall.Returns.Interpreter.Stdlib = stdlibDir
+			})
 
-			Expect(pipSrcLayer.Name).To(Equal("pip-source"))
+			it("relies on the virtual environment instead of breaking system packages", func() {
+				result, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
 
-			Expect(pipSrcLayer.Build).To(BeTrue())
-			Expect(pipSrcLayer.Launch).To(BeFalse())
-			Expect(pipSrcLayer.Cache).To(BeTrue())
+				Expect(installProcess.ExecuteCall.Receives.Options).To(Equal(pip.InstallOptions{Venv: true}))
+				for _, layer := range result.Layers {
+					Expect(layer.Name).NotTo(Equal("pip-config"))
+				}
 
-			Expect(buffer.String()).ToNot(ContainSubstring("Executing build process"))
-			Expect(buffer.String()).To(ContainSubstring("Reusing cached layer"))
+				Expect(buffer.String()).To(ContainSubstring("Strategy: installing pip into a virtual environment"))
+			})
+		})
+	})
+
+	context("when BP_PIP_SOURCE is set", func() {
+		var (
+			workingDir string
+			digest     string
+		)
+
+		it.Before(func() {
+			workingDir = t.TempDir()
+			buildContext.WorkingDir = workingDir
+
+			Expect(os.MkdirAll(filepath.Join(workingDir, "vendor"), os.ModePerm)).To(Succeed())
+			wheelPath := filepath.Join(workingDir, "vendor", "pip-24.0-py3-none-any.whl")
+			writeWheel(t, wheelPath, "pip-24.0.dist-info/METADATA", "Name: pip\nVersion: 24.0\nLicense: MIT\n")
+			writeWheel(t, filepath.Join(workingDir, "vendor", "pip-23.0-py3-none-any.whl"), "pip-23.0.dist-info/METADATA", "Name: pip\nVersion: 23.0\n")
+			writeSdist(t, filepath.Join(workingDir, "vendor", "setuptools-69.0.2.tar.gz"), "setuptools-69.0.2/PKG-INFO", "Name: setuptools\nVersion: 69.0.2\n")
+
+			content, err := os.ReadFile(wheelPath)
+			Expect(err).NotTo(HaveOccurred())
+			digest = fmt.Sprintf("%x", sha256.Sum256(content))
+
+			t.Setenv("BP_PIP_SOURCE", "./vendor/pip-24.0-py3-none-any.whl")
+			versionProcess.ExecuteCall.Returns.String = "24.0"
+
+			buildContext.Plan.Entries[0].Metadata = map[string]interface{}{
+				"build": true,
+			}
+		})
 
+		it("installs pip from the provided distribution", func() {
+			result, err := build(buildContext)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(dependencyManager.ResolveCall.CallCount).To(Equal(0))
 			Expect(dependencyManager.DeliverCall.CallCount).To(Equal(0))
-			Expect(installProcess.ExecuteCall.CallCount).To(Equal(0))
+
+			Expect(installProcess.ExecuteCall.Receives.SrcPath).To(Equal(filepath.Join(layersDir, "pip-source")))
+			Expect(filepath.Join(layersDir, "pip-source", "pip-24.0-py3-none-any.whl")).To(BeAnExistingFile())
+			Expect(filepath.Join(layersDir, "pip-source", "setuptools-69.0.2.tar.gz")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(layersDir, "pip-source", "pip-23.0-py3-none-any.whl")).NotTo(BeAnExistingFile())
+
+			Expect(result.Layers[0].Metadata["dependency_checksum"]).To(Equal("sha256:" + digest))
+
+			Expect(sbomGenerator.GenerateFromDependenciesCall.Receives.Dependencies).To(Equal([]postal.Dependency{
+				{
+					ID:       "pip",
+					Name:     "Pip",
+					Version:  "24.0",
+					Checksum: "sha256:" + digest,
+					Source:   filepath.Join(workingDir, "vendor", "pip-24.0-py3-none-any.whl"),
+					CPE:      "cpe:2.3:a:pypa:pip:24.0:*:*:*:*:python:*:*",
+					CPEs:     []string{"cpe:2.3:a:pypa:pip:24.0:*:*:*:*:python:*:*"},
+					PURL:     "pkg:pypi/pip@24.0",
+					Licenses: []string{"MIT"},
+				},
+			}))
+
+			Expect(buffer.String()).To(ContainSubstring(fmt.Sprintf("Using pip from BP_PIP_SOURCE %s", filepath.Join(workingDir, "vendor", "pip-24.0-py3-none-any.whl"))))
 		})
-	})
 
-	context("failure cases", func() {
-		context("when dependency resolution fails", func() {
+		context("when the pip layer was built from the same distribution", func() {
 			it.Before(func() {
-				dependencyManager.ResolveCall.Returns.Error = errors.New("failed to resolve dependency")
+				err := os.WriteFile(filepath.Join(layersDir, "pip.toml"), []byte(fmt.Sprintf(`[metadata]
+				%s = "sha256:%s"
+				%s = "1.23.4"
+				%s = "cpython-123-x86_64-linux-gnu"
+				%s = %q
+				%s = "user"
+				%s = "lib/python1.23/site-packages"
+				`, pip.DependencyChecksumKey, digest, pip.PythonVersionKey, pip.PythonABIKey, pip.ArchitectureKey, runtime.GOARCH, pip.InstallModeKey, pip.SitePackagesKey)), os.ModePerm)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(os.MkdirAll(filepath.Join(layersDir, "pip", "lib", "python1.23", "site-packages"), os.ModePerm)).To(Succeed())
 			})
-			it("returns an error", func() {
+
+			it("reuses the layer", func() {
 				_, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
 
-				Expect(err).To(MatchError(ContainSubstring("failed to resolve dependency")))
+				Expect(installProcess.ExecuteCall.CallCount).To(Equal(0))
+				Expect(buffer.String()).To(ContainSubstring("Reusing cached layer"))
 			})
 		})
 
-		context("when pip layer cannot be fetched", func() {
+		context("when BP_PIP_SOURCE_SHA256 matches the distribution", func() {
 			it.Before(func() {
-				Expect(os.Chmod(layersDir, 0000)).To(Succeed())
+				t.Setenv("BP_PIP_SOURCE_SHA256", "sha256:"+digest)
 			})
 
-			it.After(func() {
-				Expect(os.Chmod(layersDir, os.ModePerm)).To(Succeed())
+			it("installs pip from the provided distribution", func() {
+				_, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(installProcess.ExecuteCall.CallCount).To(Equal(1))
 			})
+		})
 
-			it("returns an error", func() {
-				_, err := build(buildContext)
+		context("failure cases", func() {
+			context("when BP_PIP_SOURCE_SHA256 does not match the distribution", func() {
+				it.Before(func() {
+					t.Setenv("BP_PIP_SOURCE_SHA256", "some-sha")
+				})
 
-				Expect(err).To(MatchError(ContainSubstring("permission denied")))
+				it("returns an error", func() {
+					_, err := build(buildContext)
+					Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf("has SHA256 %s, which does not match BP_PIP_SOURCE_SHA256 some-sha", digest))))
+				})
+			})
+
+			context("when BP_PIP_SOURCE does not exist", func() {
+				it.Before(func() {
+					t.Setenv("BP_PIP_SOURCE", "./vendor/pip-99.0-py3-none-any.whl")
+				})
+
+				it("returns an error", func() {
+					_, err := build(buildContext)
+					Expect(err).To(MatchError(ContainSubstring("failed to open BP_PIP_SOURCE")))
+				})
+			})
+
+			context("when BP_PIP_SOURCE is not a pip distribution", func() {
+				it.Before(func() {
+					t.Setenv("BP_PIP_SOURCE", "./vendor/setuptools-69.0.2.tar.gz")
+				})
+
+				it("returns an error", func() {
+					_, err := build(buildContext)
+					Expect(err).To(MatchError(ContainSubstring("setuptools-69.0.2.tar.gz is not a pip wheel or sdist")))
+				})
 			})
 		})
+	})
 
-		context("when pip layer cannot be reset", func() {
+	context("when BP_PIP_EXTRA_FIND_LINKS is set", func() {
+		it.Before(func() {
+			workingDir := t.TempDir()
+			buildContext.WorkingDir = workingDir
+
+			Expect(os.MkdirAll(filepath.Join(workingDir, "backends"), os.ModePerm)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(workingDir, "more-backends"), os.ModePerm)).To(Succeed())
+			writeWheel(t, filepath.Join(workingDir, "backends", "hatchling-1.21.0-py3-none-any.whl"), "hatchling-1.21.0.dist-info/METADATA", "Name: hatchling\nVersion: 1.21.0\nLicense-Expression: MIT\n")
+			writeSdist(t, filepath.Join(workingDir, "more-backends", "flit_core-3.9.0.tar.gz"), "flit_core-3.9.0/PKG-INFO", "Name: flit_core\nVersion: 3.9.0\n")
+			Expect(os.WriteFile(filepath.Join(workingDir, "backends", "README"), []byte("some-readme"), 0600)).To(Succeed())
+
+			t.Setenv("BP_PIP_EXTRA_FIND_LINKS", "backends:more-backends")
+
+			buildContext.Plan.Entries[0].Metadata = map[string]interface{}{
+				"build": true,
+			}
+		})
+
+		it("adds the distributions to the pip-source layer", func() {
+			result, err := build(buildContext)
+			Expect(err).NotTo(HaveOccurred())
+
+			extraDir := filepath.Join(layersDir, "pip-source", "extra-find-links")
+			Expect(filepath.Join(extraDir, "hatchling-1.21.0-py3-none-any.whl")).To(BeAnExistingFile())
+			Expect(filepath.Join(extraDir, "flit_core-3.9.0.tar.gz")).To(BeAnExistingFile())
+			Expect(filepath.Join(extraDir, "README")).NotTo(BeAnExistingFile())
+
+			Expect(installProcess.ExecuteCall.Receives.Options.FindLinks).To(Equal([]string{extraDir}))
+
+			pipSrcLayer := result.Layers[1]
+			Expect(pipSrcLayer.BuildEnv["PIP_FIND_LINKS.append"]).To(Equal(fmt.Sprintf("%s %s", filepath.Join(layersDir, "pip-source"), extraDir)))
+
+			Expect(result.Layers[0].Metadata["extra_find_links_digest"]).To(MatchRegexp(`^sha256:[0-9a-f]{64}$`))
+
+			Expect(sbomGenerator.GenerateFromDependenciesCall.Receives.Dependencies).To(ContainElements(
+				postal.Dependency{
+					ID:       "hatchling",
+					Name:     "hatchling",
+					Version:  "1.21.0",
+					PURL:     "pkg:pypi/hatchling@1.21.0",
+					Licenses: []string{"MIT"},
+				},
+				postal.Dependency{
+					ID:      "flit-core",
+					Name:    "flit-core",
+					Version: "3.9.0",
+					PURL:    "pkg:pypi/flit-core@3.9.0",
+				},
+			))
+		})
+
+		context("when the distributions have changed since the layer was built", func() {
 			it.Before(func() {
-				Expect(os.MkdirAll(filepath.Join(layersDir, pip.Pip), os.ModePerm))
-				Expect(os.Chmod(layersDir, 0500)).To(Succeed())
+				err := os.WriteFile(filepath.Join(layersDir, "pip.toml"), []byte(fmt.Sprintf(`[metadata]
+				%s = "some-sha"
+				%s = "1.23.4"
+				%s = "cpython-123-x86_64-linux-gnu"
+				%s = %q
+				%s = "user"
+				%s = "sha256:some-other-digest"
+				`, pip.DependencyChecksumKey, pip.PythonVersionKey, pip.PythonABIKey, pip.ArchitectureKey, runtime.GOARCH, pip.InstallModeKey, pip.ExtraFindLinksKey)), os.ModePerm)
+				Expect(err).NotTo(HaveOccurred())
 			})
 
-			it.After(func() {
-				Expect(os.Chmod(layersDir, os.ModePerm)).To(Succeed())
+			it("rebuilds the layers", func() {
+				_, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(installProcess.ExecuteCall.CallCount).To(Equal(1))
+				Expect(buffer.String()).To(ContainSubstring("BP_PIP_EXTRA_FIND_LINKS digest changed from sha256:some-other-digest to sha256:"))
+			})
+		})
+
+		context("when a directory does not exist", func() {
+			it.Before(func() {
+				t.Setenv("BP_PIP_EXTRA_FIND_LINKS", "no-such-dir")
 			})
 
 			it("returns an error", func() {
 				_, err := build(buildContext)
-
-				Expect(err).To(MatchError(ContainSubstring("permission denied")))
+				Expect(err).To(MatchError(ContainSubstring("failed to read BP_PIP_EXTRA_FIND_LINKS directory")))
 			})
 		})
+	})
 
-		context("when dependency cannot be installed", func() {
+	context("when BP_PIP_ADOPT_SYSTEM is true", func() {
+		it.Before(func() {
+			t.Setenv("BP_PIP_ADOPT_SYSTEM", "true")
+
+			versionProcess.ExecuteCall.Stub = func(targetLayerPath string) (string, error) {
+				if targetLayerPath == "" {
+					return "24.0", nil
+				}
+				return "21.0", nil
+			}
+
+			buildContext.Plan.Entries[0].Metadata = map[string]interface{}{
+				"launch": true,
+			}
+		})
+
+		it("adopts the pip bundled with the interpreter", func() {
+			result, err := build(buildContext)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(dependencyManager.ResolveCall.CallCount).To(Equal(0))
+			Expect(dependencyManager.DeliverCall.CallCount).To(Equal(0))
+			Expect(installProcess.ExecuteCall.CallCount).To(Equal(0))
+
+			Expect(result.Layers).To(HaveLen(1))
+			pipLayer := result.Layers[0]
+
+			Expect(pipLayer.Name).To(Equal("pip"))
+			Expect(pipLayer.Launch).To(BeTrue())
+			Expect(pipLayer.Build).To(BeFalse())
+			Expect(pipLayer.Cache).To(BeFalse())
+			Expect(pipLayer.Metadata).To(Equal(map[string]interface{}{
+				"system_pip_version": "24.0",
+			}))
+			Expect(pipLayer.SBOM.Formats()).To(HaveLen(2))
+
+			Expect(sbomGenerator.GenerateFromDependenciesCall.Receives.Dependencies).To(Equal([]postal.Dependency{
+				{
+					ID:       "pip",
+					Name:     "Pip",
+					Version:  "24.0",
+					CPE:      "cpe:2.3:a:pypa:pip:24.0:*:*:*:*:python:*:*",
+					CPEs:     []string{"cpe:2.3:a:pypa:pip:24.0:*:*:*:*:python:*:*"},
+					PURL:     "pkg:pypi/pip@24.0",
+					Licenses: []string{"MIT"},
+				},
+			}))
+			Expect(dependencyManager.GenerateBillOfMaterialsCall.Receives.Dependencies[0].Version).To(Equal("24.0"))
+
+			Expect(buffer.String()).To(ContainSubstring("System pip 24.0 adopted, skipping installation"))
+		})
+
+		context("when the system pip satisfies the requested version", func() {
 			it.Before(func() {
-				dependencyManager.DeliverCall.Returns.Error = errors.New("failed to install dependency")
+				buildContext.Plan.Entries[0].Metadata["version"] = "~24.0"
+				buildContext.Plan.Entries[0].Metadata["version-source"] = "BP_PIP_VERSION"
 			})
-			it("returns an error", func() {
+
+			it("adopts the pip bundled with the interpreter", func() {
 				_, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
 
-				Expect(err).To(MatchError(ContainSubstring("failed to install dependency")))
+				Expect(installProcess.ExecuteCall.CallCount).To(Equal(0))
 			})
 		})
 
-		context("when the site packages cannot be found", func() {
+		context("when the system pip does not satisfy the requested version", func() {
 			it.Before(func() {
-				sitePackageProcess.ExecuteCall.Returns.Error = errors.New("failed to find site-packages dir")
+				buildContext.Plan.Entries[0].Metadata["version"] = "21.0.0"
+				buildContext.Plan.Entries[0].Metadata["version-source"] = "BP_PIP_VERSION"
 			})
 
-			it("returns an error", func() {
+			it("installs pip", func() {
 				_, err := build(buildContext)
-				Expect(err).To(MatchError(ContainSubstring("failed to find site-packages dir")))
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(dependencyManager.DeliverCall.CallCount).To(Equal(1))
+				Expect(installProcess.ExecuteCall.CallCount).To(Equal(1))
+
+				Expect(buffer.String()).To(ContainSubstring("System pip 24.0 does not satisfy 21.0.0"))
 			})
 		})
 
-		context("when the layer does not have a site-packages directory", func() {
+		context("when no pip is bundled with the interpreter", func() {
 			it.Before(func() {
-				sitePackageProcess.ExecuteCall.Returns.String = ""
+				versionProcess.ExecuteCall.Stub = func(targetLayerPath string) (string, error) {
+					if targetLayerPath == "" {
+						return "", errors.New("No module named pip")
+					}
+					return "21.0", nil
+				}
 			})
 
-			it("returns an error", func() {
+			it("installs pip", func() {
 				_, err := build(buildContext)
-				Expect(err).To(MatchError(ContainSubstring("pip installation failed: site packages are missing from the pip layer")))
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(installProcess.ExecuteCall.CallCount).To(Equal(1))
+
+				Expect(buffer.String()).To(ContainSubstring("No pip is bundled with the interpreter"))
 			})
 		})
 
-		context("when generating the SBOM returns an error", func() {
+		context("when BP_PIP_ADOPT_SYSTEM cannot be parsed", func() {
 			it.Before(func() {
-				buildContext.BuildpackInfo.SBOMFormats = []string{"random-format"}
+				t.Setenv("BP_PIP_ADOPT_SYSTEM", "some-value")
 			})
 
 			it("returns an error", func() {
 				_, err := build(buildContext)
-				Expect(err).To(MatchError(`unsupported SBOM format: 'random-format'`))
+				Expect(err).To(MatchError(ContainSubstring(`failed to parse BP_PIP_ADOPT_SYSTEM value "some-value"`)))
 			})
 		})
+	})
 
-		context("when formatting the SBOM returns an error", func() {
+	context("when the version of pip is not a semver version", func() {
+		it("verifies the installed pip against its PEP 440 version", func() {
+			for dependencyVersion, installedVersion := range map[string]string{
+				"24.0.post1": "24.0.post1",
+				"24.1rc1":    "24.1RC1",
+				"24.1.0":     "24.1",
+			} {
+				dependencyManager.ResolveCall.Returns.Dependency.Version = dependencyVersion
+				versionProcess.ExecuteCall.Returns.String = installedVersion
+
+				_, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred(), dependencyVersion)
+			}
+		})
+	})
+
+	context("when the requested version is system", func() {
+		it.Before(func() {
+			versionProcess.ExecuteCall.Stub = func(targetLayerPath string) (string, error) {
+				if targetLayerPath == "" {
+					return "24.0", nil
+				}
+				return "21.0", nil
+			}
+
+			buildContext.Plan.Entries[0].Metadata = map[string]interface{}{
+				"version":        "system",
+				"version-source": "BP_PIP_VERSION",
+			}
+		})
+
+		it("adopts the pip bundled with the interpreter", func() {
+			_, err := build(buildContext)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(dependencyManager.ResolveCall.CallCount).To(Equal(0))
+			Expect(installProcess.ExecuteCall.CallCount).To(Equal(0))
+
+			Expect(buffer.String()).To(ContainSubstring("System pip 24.0 adopted, skipping installation"))
+		})
+
+		context("when no pip is bundled with the interpreter", func() {
 			it.Before(func() {
-				sbomGenerator.GenerateFromDependencyCall.Returns.Error = errors.New("failed to generate SBOM")
+				versionProcess.ExecuteCall.Stub = func(targetLayerPath string) (string, error) {
+					if targetLayerPath == "" {
+						return "", errors.New("No module named pip")
+					}
+					return "21.0", nil
+				}
 			})
 
-			it("returns an error", func() {
+			it("installs the default version of pip", func() {
 				_, err := build(buildContext)
-				Expect(err).To(MatchError(ContainSubstring("failed to generate SBOM")))
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(dependencyManager.DeliverCall.CallCount).To(Equal(1))
+				Expect(installProcess.ExecuteCall.CallCount).To(Equal(1))
+
+				Expect(buffer.String()).To(ContainSubstring("No pip is bundled with the interpreter"))
 			})
 		})
 	})
+
+	context("when build plan entries require pip at build/launch", func() {
+		it.Before(func() {
+			buildContext.Plan.Entries[0].Metadata = make(map[string]interface{})
+			buildContext.Plan.Entries[0].Metadata["build"] = true
+			buildContext.Plan.Entries[0].Metadata["launch"] = true
+		})
+
+		it("makes the layer available at the right times", func() {
+			result, err := build(buildContext)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers).To(HaveLen(3))
+			pipLayer := result.Layers[0]
+
+			Expect(pipLayer.Name).To(Equal("pip"))
+
+			Expect(pipLayer.Build).To(BeTrue())
+			Expect(pipLayer.Launch).To(BeTrue())
+			Expect(pipLayer.Cache).To(BeTrue())
+
+			pipSrcLayer := result.Layers[1]
+
+			Expect(pipSrcLayer.Name).To(Equal("pip-source"))
+
+			Expect(pipSrcLayer.Build).To(BeTrue())
+			Expect(pipSrcLayer.Launch).To(BeFalse())
+			Expect(pipSrcLayer.Cache).To(BeTrue())
+
+			pipCacheLayer := result.Layers[2]
+
+			Expect(pipCacheLayer.Name).To(Equal("pip-cache"))
+
+			Expect(pipCacheLayer.Build).To(BeTrue())
+			Expect(pipCacheLayer.Launch).To(BeFalse())
+			Expect(pipCacheLayer.Cache).To(BeTrue())
+
+			Expect(pipCacheLayer.BuildEnv).To(Equal(packit.Environment{
+				"PIP_CACHE_DIR.override": filepath.Join(layersDir, "pip-cache"),
+			}))
+			Expect(pipCacheLayer.SharedEnv).To(BeEmpty())
+			Expect(pipCacheLayer.LaunchEnv).To(BeEmpty())
+
+			Expect(result.Build.BOM).To(Equal(
+				[]packit.BOMEntry{
+					{
+						Name: "pip",
+						Metadata: paketosbom.BOMMetadata{
+							Checksum: paketosbom.BOMChecksum{
+								Algorithm: paketosbom.SHA256,
+								Hash:      "pip-dependency-sha",
+							},
+							URI:     "pip-dependency-uri",
+							Version: "pip-dependency-version",
+						},
+					},
+				},
+			))
+
+			Expect(result.Launch.BOM).To(Equal(
+				[]packit.BOMEntry{
+					{
+						Name: "pip",
+						Metadata: paketosbom.BOMMetadata{
+							Checksum: paketosbom.BOMChecksum{
+								Algorithm: paketosbom.SHA256,
+								Hash:      "pip-dependency-sha",
+							},
+							URI:     "pip-dependency-uri",
+							Version: "pip-dependency-version",
+						},
+					},
+				},
+			))
+		})
+	})
+
+	context("when multiple sources request a version of pip", func() {
+		it.Before(func() {
+			buildContext.Plan.Entries = []packit.BuildpackPlanEntry{
+				{
+					Name: "pip",
+					Metadata: map[string]interface{}{
+						"version-source": "pyproject.toml",
+						"version":        "23.*",
+					},
+				},
+				{
+					Name: "pip",
+					Metadata: map[string]interface{}{
+						"version-source": ".pip-version",
+						"version":        "22.3.0",
+					},
+				},
+			}
+		})
+
+		it("resolves the version from the highest priority source", func() {
+			_, err := build(buildContext)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(dependencyManager.ResolveCall.Receives.Version).To(Equal("22.3.0"))
+			Expect(buffer.String()).To(ContainSubstring("Selected Pip version (using .pip-version)"))
+		})
+
+		context("when BP_PIP_VERSION is also requested", func() {
+			it.Before(func() {
+				buildContext.Plan.Entries = append(buildContext.Plan.Entries, packit.BuildpackPlanEntry{
+					Name: "pip",
+					Metadata: map[string]interface{}{
+						"version-source": "BP_PIP_VERSION",
+						"version":        "21.0.0",
+					},
+				})
+			})
+
+			it("resolves the version from BP_PIP_VERSION", func() {
+				_, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(dependencyManager.ResolveCall.Receives.Version).To(Equal("21.0.0"))
+			})
+		})
+	})
+
+	context("when the pip dependencies declare the python versions they support", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(cnbDir, "buildpack.toml"), []byte(`
+[[metadata.dependencies]]
+  id = "pip"
+  requires-python = ">=1.20"
+  stacks = ["*"]
+  version = "21.0.0"
+
+[[metadata.dependencies]]
+  id = "pip"
+  requires-python = ">=1.20"
+  stacks = ["some-stack"]
+  version = "22.0.0"
+
+[[metadata.dependencies]]
+  id = "pip"
+  requires-python = ">=1.30"
+  stacks = ["*"]
+  version = "23.0.0"
+
+[[metadata.dependencies]]
+  id = "pip"
+  requires-python = ">=1.20"
+  stacks = ["other-stack"]
+  version = "24.0.0"
+`), 0600)).To(Succeed())
+		})
+
+		it("resolves the newest version that supports the python interpreter", func() {
+			_, err := build(buildContext)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(interpreterProcess.ExecuteCall.CallCount).To(Equal(1))
+			Expect(dependencyManager.ResolveCall.Receives.Version).To(Equal("22.0.0"))
+			Expect(buffer.String()).To(ContainSubstring("Skipping pip 23.0.0: it requires Python >=1.30, which is not compatible with the installed Python 1.23.4"))
+		})
+
+		context("when the requested version supports the python interpreter", func() {
+			it.Before(func() {
+				buildContext.Plan.Entries[0].Metadata = map[string]interface{}{
+					"version-source": "BP_PIP_VERSION",
+					"version":        "21.*",
+				}
+			})
+
+			it("passes the requested version to dependency resolution", func() {
+				_, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(dependencyManager.ResolveCall.Receives.Version).To(Equal("21.*"))
+			})
+		})
+
+		context("when the default version supports the python interpreter", func() {
+			it.Before(func() {
+				content, err := os.ReadFile(filepath.Join(cnbDir, "buildpack.toml"))
+				Expect(err).NotTo(HaveOccurred())
+
+				content = append([]byte(`
+[metadata.default-versions]
+  pip = "21.*"
+`), content...)
+				Expect(os.WriteFile(filepath.Join(cnbDir, "buildpack.toml"), content, 0600)).To(Succeed())
+			})
+
+			it("leaves the default version to dependency resolution", func() {
+				_, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(dependencyManager.ResolveCall.Receives.Version).To(Equal(""))
+			})
+		})
+
+		context("when the requested version does not exist", func() {
+			it.Before(func() {
+				buildContext.Plan.Entries[0].Metadata = map[string]interface{}{
+					"version-source": "BP_PIP_VERSION",
+					"version":        "99.*",
+				}
+			})
+
+			it("passes the requested version to dependency resolution", func() {
+				_, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(dependencyManager.ResolveCall.Receives.Version).To(Equal("99.*"))
+			})
+		})
+	})
+
+	context("when a pip service binding is provided", func() {
+		it.Before(func() {
+			buildContext.Plan.Entries[0].Metadata = map[string]interface{}{
+				"build": true,
+			}
+
+			bindings["pip"] = []servicebindings.Binding{
+				{
+					Name: "some-binding",
+					Path: "/bindings/some-binding",
+					Type: "pip",
+					Entries: map[string]*servicebindings.Entry{
+						"pip.conf": servicebindings.NewEntry("/bindings/some-binding/pip.conf"),
+					},
+				},
+			}
+		})
+
+		it("configures pip from the binding in a build-only layer", func() {
+			result, err := build(buildContext)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers).To(HaveLen(4))
+			pipConfigLayer := result.Layers[2]
+
+			Expect(pipConfigLayer.Name).To(Equal("pip-config"))
+			Expect(pipConfigLayer.Path).To(Equal(filepath.Join(layersDir, "pip-config")))
+
+			Expect(pipConfigLayer.Build).To(BeTrue())
+			Expect(pipConfigLayer.Launch).To(BeFalse())
+			Expect(pipConfigLayer.Cache).To(BeFalse())
+
+			Expect(pipConfigLayer.BuildEnv).To(Equal(packit.Environment{
+				"PIP_CONFIG_FILE.override": "/bindings/some-binding/pip.conf",
+			}))
+			Expect(pipConfigLayer.SharedEnv).To(BeEmpty())
+			Expect(pipConfigLayer.LaunchEnv).To(BeEmpty())
+
+			Expect(buffer.String()).To(ContainSubstring("Configuring pip from binding 'some-binding'"))
+		})
+
+		context("when the binding contains a .netrc", func() {
+			it.Before(func() {
+				bindings["pip"][0].Entries[".netrc"] = servicebindings.NewEntry("/bindings/some-binding/.netrc")
+			})
+
+			it("configures pip to use the credentials from the binding", func() {
+				result, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers).To(HaveLen(4))
+				Expect(result.Layers[2].BuildEnv).To(Equal(packit.Environment{
+					"PIP_CONFIG_FILE.override": "/bindings/some-binding/pip.conf",
+					"NETRC.override":           "/bindings/some-binding/.netrc",
+				}))
+			})
+		})
+
+		context("when the pip layer is reused", func() {
+			it.Before(func() {
+				err := os.WriteFile(filepath.Join(layersDir, fmt.Sprintf("%s.toml", pip.Pip)), []byte(fmt.Sprintf(`[metadata]
+				%s = "some-sha"
+				%s = "1.23.4"
+				%s = "cpython-123-x86_64-linux-gnu"
+				%s = %q
+				%s = "user"
+				%s = "lib/python1.23/site-packages"
+				`, pip.DependencyChecksumKey, pip.PythonVersionKey, pip.PythonABIKey, pip.ArchitectureKey, runtime.GOARCH, pip.InstallModeKey, pip.SitePackagesKey)), os.ModePerm)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(os.MkdirAll(filepath.Join(layersDir, "pip", "lib", "python1.23", "site-packages"), os.ModePerm)).To(Succeed())
+			})
+
+			it("still configures pip from the binding", func() {
+				result, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).To(ContainSubstring("Reusing cached layer"))
+
+				Expect(result.Layers).To(HaveLen(4))
+				Expect(result.Layers[2].Name).To(Equal("pip-config"))
+				Expect(result.Layers[2].BuildEnv).To(HaveKeyWithValue("PIP_CONFIG_FILE.override", "/bindings/some-binding/pip.conf"))
+			})
+		})
+	})
+
+	context("when ca-certificates service bindings are provided", func() {
+		var (
+			bindingsDir    string
+			systemCertFile string
+		)
+
+		it.Before(func() {
+			buildContext.Plan.Entries[0].Metadata = map[string]interface{}{
+				"build": true,
+			}
+
+			var err error
+			bindingsDir, err = os.MkdirTemp("", "bindings")
+			Expect(err).NotTo(HaveOccurred())
+
+			systemCertFile = filepath.Join(bindingsDir, "system.crt")
+			Expect(os.WriteFile(systemCertFile, []byte("system-certificate"), 0600)).To(Succeed())
+			Expect(os.Setenv("SSL_CERT_FILE", systemCertFile)).To(Succeed())
+
+			Expect(os.MkdirAll(filepath.Join(bindingsDir, "some-binding"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(bindingsDir, "some-binding", "b.pem"), []byte("some-certificate-b\n"), 0600)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(bindingsDir, "some-binding", "a.pem"), []byte("some-certificate-a"), 0600)).To(Succeed())
+
+			Expect(os.MkdirAll(filepath.Join(bindingsDir, "other-binding"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(bindingsDir, "other-binding", "ca.pem"), []byte("other-certificate\n"), 0600)).To(Succeed())
+
+			bindings["ca-certificates"] = []servicebindings.Binding{
+				{
+					Name: "some-binding",
+					Path: filepath.Join(bindingsDir, "some-binding"),
+					Type: "ca-certificates",
+					Entries: map[string]*servicebindings.Entry{
+						"b.pem": servicebindings.NewEntry(filepath.Join(bindingsDir, "some-binding", "b.pem")),
+						"a.pem": servicebindings.NewEntry(filepath.Join(bindingsDir, "some-binding", "a.pem")),
+					},
+				},
+				{
+					Name: "other-binding",
+					Path: filepath.Join(bindingsDir, "other-binding"),
+					Type: "ca-certificates",
+					Entries: map[string]*servicebindings.Entry{
+						"ca.pem": servicebindings.NewEntry(filepath.Join(bindingsDir, "other-binding", "ca.pem")),
+					},
+				},
+			}
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("SSL_CERT_FILE")).To(Succeed())
+			Expect(os.RemoveAll(bindingsDir)).To(Succeed())
+		})
+
+		it("writes a combined CA bundle into a build-only layer", func() {
+			result, err := build(buildContext)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers).To(HaveLen(4))
+			pipConfigLayer := result.Layers[2]
+
+			Expect(pipConfigLayer.Name).To(Equal("pip-config"))
+			Expect(pipConfigLayer.Build).To(BeTrue())
+			Expect(pipConfigLayer.Launch).To(BeFalse())
+			Expect(pipConfigLayer.Cache).To(BeFalse())
+
+			bundlePath := filepath.Join(layersDir, "pip-config", "ca-bundle.pem")
+			Expect(pipConfigLayer.BuildEnv).To(Equal(packit.Environment{
+				"PIP_CERT.override": bundlePath,
+			}))
+			Expect(pipConfigLayer.SharedEnv).To(BeEmpty())
+			Expect(pipConfigLayer.LaunchEnv).To(BeEmpty())
+
+			contents, err := os.ReadFile(bundlePath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal("system-certificate\nsome-certificate-a\nsome-certificate-b\nother-certificate\n"))
+
+			Expect(result.Layers[0].LaunchEnv).To(BeEmpty())
+			Expect(result.Layers[1].BuildEnv).To(HaveKey("PIP_FIND_LINKS.append"))
+
+			Expect(buffer.String()).To(ContainSubstring("Adding CA certificates from 2 binding(s) of type 'ca-certificates'"))
+		})
+
+		context("when a pip binding is also provided", func() {
+			it.Before(func() {
+				bindings["pip"] = []servicebindings.Binding{
+					{
+						Name: "some-pip-binding",
+						Path: "/bindings/some-pip-binding",
+						Type: "pip",
+						Entries: map[string]*servicebindings.Entry{
+							"pip.conf": servicebindings.NewEntry("/bindings/some-pip-binding/pip.conf"),
+						},
+					},
+				}
+			})
+
+			it("configures both in the same layer", func() {
+				result, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers).To(HaveLen(4))
+				Expect(result.Layers[2].BuildEnv).To(Equal(packit.Environment{
+					"PIP_CONFIG_FILE.override": "/bindings/some-pip-binding/pip.conf",
+					"PIP_CERT.override":        filepath.Join(layersDir, "pip-config", "ca-bundle.pem"),
+				}))
+			})
+		})
+
+		context("when the system CA certificates do not exist", func() {
+			it.Before(func() {
+				Expect(os.Remove(systemCertFile)).To(Succeed())
+			})
+
+			it("writes a bundle containing only the bound certificates", func() {
+				_, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				contents, err := os.ReadFile(filepath.Join(layersDir, "pip-config", "ca-bundle.pem"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(contents)).To(Equal("some-certificate-a\nsome-certificate-b\nother-certificate\n"))
+			})
+		})
+
+		context("failure cases", func() {
+			context("when a certificate cannot be read", func() {
+				it.Before(func() {
+					Expect(os.Remove(filepath.Join(bindingsDir, "other-binding", "ca.pem"))).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := build(buildContext)
+					Expect(err).To(MatchError(ContainSubstring("failed to read certificate 'ca.pem' from binding 'other-binding'")))
+				})
+			})
+		})
+	})
+
+	context("when the pip cache layer exceeds its size limit", func() {
+		it.Before(func() {
+			buildContext.Plan.Entries[0].Metadata = map[string]interface{}{
+				"build": true,
+			}
+
+			cacheDir := filepath.Join(layersDir, "pip-cache")
+			Expect(os.MkdirAll(filepath.Join(cacheDir, "http"), os.ModePerm)).To(Succeed())
+
+			for i, name := range []string{"oldest", "older", "newest"} {
+				path := filepath.Join(cacheDir, "http", name)
+				Expect(os.WriteFile(path, bytes.Repeat([]byte("x"), 1024), 0600)).To(Succeed())
+
+				modTime := time.Now().Add(time.Duration(i-3) * time.Hour)
+				Expect(os.Chtimes(path, modTime, modTime)).To(Succeed())
+			}
+
+			Expect(os.Setenv("BP_PIP_CACHE_MAX_SIZE", "2KiB")).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_PIP_CACHE_MAX_SIZE")).To(Succeed())
+		})
+
+		it("prunes the least recently used files and reports the usage", func() {
+			result, err := build(buildContext)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers).To(HaveLen(3))
+			Expect(result.Layers[2].Name).To(Equal("pip-cache"))
+
+			Expect(filepath.Join(layersDir, "pip-cache", "http", "oldest")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(layersDir, "pip-cache", "http", "older")).To(BeAnExistingFile())
+			Expect(filepath.Join(layersDir, "pip-cache", "http", "newest")).To(BeAnExistingFile())
+
+			Expect(buffer.String()).To(ContainSubstring("Configuring pip cache"))
+			Expect(buffer.String()).To(ContainSubstring("Using 2.0 KiB of 2.0 KiB"))
+			Expect(buffer.String()).To(ContainSubstring("Pruned from 3.0 KiB"))
+		})
+
+		context("when the size limit cannot be parsed", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_PIP_CACHE_MAX_SIZE", "some-size")).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError(ContainSubstring("failed to parse BP_PIP_CACHE_MAX_SIZE")))
+			})
+		})
+	})
+
+	context("when distributions are bundled with pip", func() {
+		it.Before(func() {
+			dependencyManager.DeliverCall.Stub = func(dependency postal.Dependency, cnbPath, destinationPath, platformPath string) error {
+				writeWheel(t, filepath.Join(destinationPath, "wheel-0.42.0-py3-none-any.whl"), "wheel-0.42.0.dist-info/METADATA", `Metadata-Version: 2.1
+Name: wheel
+Version: 0.42.0
+Classifier: License :: OSI Approved :: MIT License
+
+A built-package format for Python
+`)
+				writeWheel(t, filepath.Join(destinationPath, "pip-21.0-py3-none-any.whl"), "pip-21.0.dist-info/METADATA", "Name: pip\nVersion: 21.0\n")
+				writeSdist(t, filepath.Join(destinationPath, "setuptools-69.0.2.tar.gz"), "setuptools-69.0.2/PKG-INFO", `Metadata-Version: 2.1
+Name: setuptools
+Version: 69.0.2
+License: UNKNOWN
+License-Expression: MIT
+`)
+				writeSdist(t, filepath.Join(destinationPath, "flit_core-3.9.0.tar.gz"), "flit_core-3.9.0/PKG-INFO", `Metadata-Version: 2.1
+Name: flit_core
+Version: 3.9.0
+License: BSD-3-Clause
+`)
+				writeSdist(t, filepath.Join(destinationPath, "wheel-0.42.0.tar.gz"), "wheel-0.42.0/PKG-INFO", "Name: wheel\nVersion: 0.42.0\n")
+				return os.WriteFile(filepath.Join(destinationPath, "PKG-INFO"), []byte("Name: pip\nVersion: 21.0\n"), 0600)
+			}
+		})
+
+		it("lists the bundled distributions in the SBOM of both layers", func() {
+			result, err := build(buildContext)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(sbomGenerator.GenerateFromDependenciesCall.CallCount).To(Equal(2))
+			Expect(sbomGenerator.GenerateFromDependenciesCall.Receives.Dependencies).To(Equal([]postal.Dependency{
+				dependencyManager.ResolveCall.Returns.Dependency,
+				{
+					ID:       "flit-core",
+					Name:     "flit-core",
+					Version:  "3.9.0",
+					PURL:     "pkg:pypi/flit-core@3.9.0",
+					Licenses: []string{"BSD-3-Clause"},
+				},
+				{
+					ID:       "setuptools",
+					Name:     "setuptools",
+					Version:  "69.0.2",
+					PURL:     "pkg:pypi/setuptools@69.0.2",
+					Licenses: []string{"MIT"},
+				},
+				{
+					ID:       "wheel",
+					Name:     "wheel",
+					Version:  "0.42.0",
+					PURL:     "pkg:pypi/wheel@0.42.0",
+					Licenses: []string{"MIT License"},
+				},
+			}))
+
+			Expect(result.Layers[0].SBOM.Formats()).To(HaveLen(2))
+			Expect(result.Layers[1].SBOM.Formats()).To(HaveLen(2))
+
+			Expect(buffer.String()).To(ContainSubstring("Bundled setuptools 69.0.2"))
+		})
+
+		it("pins the bundled distributions through PIP_CONSTRAINT", func() {
+			result, err := build(buildContext)
+			Expect(err).NotTo(HaveOccurred())
+
+			constraintsPath := filepath.Join(layersDir, "pip-source", "constraints.txt")
+			Expect(result.Layers[1].BuildEnv).To(HaveKeyWithValue("PIP_CONSTRAINT.append", constraintsPath))
+			Expect(result.Layers[1].BuildEnv).To(HaveKeyWithValue("PIP_CONSTRAINT.delim", " "))
+			Expect(result.Layers[0].SharedEnv).NotTo(HaveKey("PIP_CONSTRAINT.append"))
+
+			content, err := os.ReadFile(constraintsPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(Equal("flit-core==3.9.0\nsetuptools==69.0.2\nwheel==0.42.0\n"))
+		})
+
+		context("when BP_PIP_DISABLE_CONSTRAINTS is true", func() {
+			it.Before(func() {
+				t.Setenv("BP_PIP_DISABLE_CONSTRAINTS", "true")
+			})
+
+			it("does not pin the bundled distributions", func() {
+				result, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(filepath.Join(layersDir, "pip-source", "constraints.txt")).NotTo(BeAnExistingFile())
+				Expect(result.Layers[1].BuildEnv).NotTo(HaveKey("PIP_CONSTRAINT.append"))
+				Expect(result.Layers[0].Metadata["constraints"]).To(Equal("disabled"))
+			})
+
+			context("when the layer was built with constraints", func() {
+				it.Before(func() {
+					err := os.WriteFile(filepath.Join(layersDir, "pip.toml"), []byte(fmt.Sprintf(`[metadata]
+					%s = "some-sha"
+					%s = "1.23.4"
+					%s = "cpython-123-x86_64-linux-gnu"
+					%s = %q
+					%s = "user"
+					`, pip.DependencyChecksumKey, pip.PythonVersionKey, pip.PythonABIKey, pip.ArchitectureKey, runtime.GOARCH, pip.InstallModeKey)), os.ModePerm)
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				it("rebuilds the layers", func() {
+					_, err := build(buildContext)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(installProcess.ExecuteCall.CallCount).To(Equal(1))
+					Expect(buffer.String()).To(ContainSubstring("bundled constraints changed from enabled to disabled"))
+				})
+			})
+
+			context("when the value cannot be parsed", func() {
+				it.Before(func() {
+					t.Setenv("BP_PIP_DISABLE_CONSTRAINTS", "some-value")
+				})
+
+				it("returns an error", func() {
+					_, err := build(buildContext)
+					Expect(err).To(MatchError(ContainSubstring(`failed to parse BP_PIP_DISABLE_CONSTRAINTS value "some-value"`)))
+				})
+			})
+		})
+
+		context("when a bundled distribution is malformed", func() {
+			it.Before(func() {
+				dependencyManager.DeliverCall.Stub = func(dependency postal.Dependency, cnbPath, destinationPath, platformPath string) error {
+					return os.WriteFile(filepath.Join(destinationPath, "setuptools-69.0.2.tar.gz"), []byte("not a tarball"), 0600)
+				}
+			})
+
+			it("returns an error", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError(ContainSubstring("failed to read metadata of bundled distribution setuptools-69.0.2.tar.gz")))
+			})
+		})
+
+		context("when vulnerability advisories are provided", func() {
+			var workingDir string
+
+			it.Before(func() {
+				workingDir = t.TempDir()
+				buildContext.WorkingDir = workingDir
+
+				Expect(os.WriteFile(filepath.Join(workingDir, "advisories.json"), []byte(`[
+					{
+						"id": "GHSA-pip-1",
+						"affected": [{
+							"package": {"ecosystem": "PyPI", "name": "pip"},
+							"ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "21.1"}]}]
+						}]
+					},
+					{
+						"id": "PYSEC-pip-2",
+						"affected": [{
+							"package": {"ecosystem": "PyPI", "name": "pip"},
+							"ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "20.0"}, {"last_affected": "21.0"}]}]
+						}]
+					},
+					{
+						"id": "GHSA-pip-3",
+						"affected": [{
+							"package": {"ecosystem": "PyPI", "name": "pip"},
+							"ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "20.3"}]}]
+						}]
+					},
+					{
+						"id": "GHSA-pip-rc",
+						"affected": [{
+							"package": {"ecosystem": "PyPI", "name": "pip"},
+							"ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "21.0rc1"}]}]
+						}]
+					},
+					{
+						"id": "GHSA-pip-post",
+						"affected": [{
+							"package": {"ecosystem": "PyPI", "name": "pip"},
+							"ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "21.0.post1"}]}]
+						}]
+					},
+					{
+						"id": "GHSA-setuptools-1",
+						"affected": [{
+							"package": {"ecosystem": "PyPI", "name": "setuptools"},
+							"versions": ["69.0.1", "69.0.2"]
+						}]
+					},
+					{
+						"id": "GHSA-wheel-1",
+						"affected": [{
+							"package": {"ecosystem": "PyPI", "name": "wheel"},
+							"ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "0.38.1"}]}]
+						}]
+					},
+					{
+						"id": "GHSA-npm-wheel",
+						"affected": [{
+							"package": {"ecosystem": "npm", "name": "wheel"},
+							"versions": ["0.42.0"]
+						}]
+					}
+				]`), 0600)).To(Succeed())
+
+				t.Setenv("BP_PIP_ADVISORIES", "advisories.json")
+			})
+
+			it("warns about the advisories that affect pip and the bundled distributions", func() {
+				_, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).To(ContainLines(
+					"  Warning: found known vulnerabilities",
+					"    pip 21.0 (GHSA-pip-1, PYSEC-pip-2)",
+				))
+				Expect(buffer.String()).To(ContainLines(
+					"  Warning: found known vulnerabilities",
+					"    setuptools 69.0.2 (GHSA-setuptools-1)",
+				))
+				Expect(buffer.String()).NotTo(ContainSubstring("GHSA-pip-3"))
+				Expect(buffer.String()).NotTo(ContainSubstring("GHSA-pip-rc"))
+				Expect(buffer.String()).To(ContainLines(
+					"  Warning: skipped advisories that could not be checked",
+					`    GHSA-pip-post for pip 21.0: failed to parse version "21.0.post1": invalid semantic version`,
+				))
+				Expect(buffer.String()).NotTo(ContainSubstring("GHSA-wheel-1"))
+				Expect(buffer.String()).NotTo(ContainSubstring("GHSA-npm-wheel"))
+			})
+
+			context("when BP_PIP_VULN_POLICY is fail", func() {
+				it.Before(func() {
+					t.Setenv("BP_PIP_VULN_POLICY", "fail")
+				})
+
+				it("fails before installing pip", func() {
+					_, err := build(buildContext)
+					Expect(err).To(MatchError("found known vulnerabilities with BP_PIP_VULN_POLICY=fail: pip 21.0 (GHSA-pip-1, PYSEC-pip-2)"))
+					Expect(installProcess.ExecuteCall.CallCount).To(Equal(0))
+				})
+
+				context("when only a bundled distribution is affected", func() {
+					it.Before(func() {
+						dependencyManager.ResolveCall.Returns.Dependency.Version = "21.1"
+						versionProcess.ExecuteCall.Returns.String = "21.1"
+					})
+
+					it("fails naming the bundled distribution", func() {
+						_, err := build(buildContext)
+						Expect(err).To(MatchError("found known vulnerabilities with BP_PIP_VULN_POLICY=fail: setuptools 69.0.2 (GHSA-setuptools-1)"))
+					})
+				})
+			})
+
+			context("when the advisories are provided by a binding", func() {
+				it.Before(func() {
+					t.Setenv("BP_PIP_ADVISORIES", "")
+					t.Setenv("BP_PIP_VULN_POLICY", "fail")
+
+					Expect(os.WriteFile(filepath.Join(workingDir, "osv.json"), []byte(`{
+						"vulns": [{
+							"id": "GHSA-pip-4",
+							"affected": [{
+								"package": {"ecosystem": "PyPI", "name": "pip"},
+								"versions": ["21.0"]
+							}]
+						}]
+					}`), 0600)).To(Succeed())
+
+					bindings["pip-advisories"] = []servicebindings.Binding{
+						{
+							Name: "some-advisories",
+							Type: "pip-advisories",
+							Entries: map[string]*servicebindings.Entry{
+								"osv.json": servicebindings.NewEntry(filepath.Join(workingDir, "osv.json")),
+							},
+						},
+					}
+				})
+
+				it("matches them against pip", func() {
+					_, err := build(buildContext)
+					Expect(err).To(MatchError("found known vulnerabilities with BP_PIP_VULN_POLICY=fail: pip 21.0 (GHSA-pip-4)"))
+				})
+			})
+
+			context("when BP_PIP_VULN_POLICY is not supported", func() {
+				it.Before(func() {
+					t.Setenv("BP_PIP_VULN_POLICY", "ignore")
+				})
+
+				it("returns an error", func() {
+					_, err := build(buildContext)
+					Expect(err).To(MatchError(`unsupported BP_PIP_VULN_POLICY "ignore": expected "warn" or "fail"`))
+				})
+			})
+
+			context("when the advisories cannot be parsed", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(filepath.Join(workingDir, "advisories.json"), []byte("%%%"), 0600)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := build(buildContext)
+					Expect(err).To(MatchError(ContainSubstring("failed to parse BP_PIP_ADVISORIES")))
+				})
+			})
+		})
+	})
+
+	context("when rebuilding a layer", func() {
+		it.Before(func() {
+			err := os.WriteFile(filepath.Join(layersDir, fmt.Sprintf("%s.toml", pip.Pip)), []byte(fmt.Sprintf(`[metadata]
+			%s = "some-sha"
+			%s = "1.23.4"
+			%s = "cpython-123-x86_64-linux-gnu"
+			%s = %q
+			%s = "user"
+			%s = "lib/python1.23/site-packages"
+			built_at = "some-build-time"
+			`, pip.DependencyChecksumKey, pip.PythonVersionKey, pip.PythonABIKey, pip.ArchitectureKey, runtime.GOARCH, pip.InstallModeKey, pip.SitePackagesKey)), os.ModePerm)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.MkdirAll(filepath.Join(layersDir, "pip", "lib", "python1.23", "site-packages"), os.ModePerm)).To(Succeed())
+
+			buildContext.Plan.Entries[0].Metadata = make(map[string]interface{})
+			buildContext.Plan.Entries[0].Metadata["build"] = true
+			buildContext.Plan.Entries[0].Metadata["launch"] = false
+		})
+
+		it("skips the build process if the cached dependency sha matches the selected dependency sha", func() {
+			result, err := build(buildContext)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers).To(HaveLen(3))
+			pipLayer := result.Layers[0]
+
+			Expect(pipLayer.Name).To(Equal("pip"))
+
+			Expect(pipLayer.Build).To(BeTrue())
+			Expect(pipLayer.Launch).To(BeFalse())
+			Expect(pipLayer.Cache).To(BeTrue())
+
+			pipSrcLayer := result.Layers[1]
+
+			Expect(pipSrcLayer.Name).To(Equal("pip-source"))
+
+			Expect(pipSrcLayer.Build).To(BeTrue())
+			Expect(pipSrcLayer.Launch).To(BeFalse())
+			Expect(pipSrcLayer.Cache).To(BeTrue())
+
+			Expect(buffer.String()).ToNot(ContainSubstring("Executing build process"))
+			Expect(buffer.String()).To(ContainSubstring("Reusing cached layer"))
+
+			Expect(dependencyManager.DeliverCall.CallCount).To(Equal(0))
+			Expect(installProcess.ExecuteCall.CallCount).To(Equal(0))
+			Expect(sitePackageProcess.ExecuteCall.CallCount).To(Equal(0))
+
+			Expect(pipLayer.SharedEnv).To(HaveKeyWithValue("PYTHONPATH.prepend", filepath.Join(layersDir, "pip", "lib", "python1.23", "site-packages")))
+		})
+
+		context("when the recorded site packages directory is missing from the layer", func() {
+			it.Before(func() {
+				Expect(os.RemoveAll(filepath.Join(layersDir, "pip", "lib"))).To(Succeed())
+			})
+
+			it("rebuilds the layer", func() {
+				result, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).To(ContainSubstring(fmt.Sprintf("Rebuilding cached layer %s: site packages directory lib/python1.23/site-packages is missing", filepath.Join(layersDir, "pip"))))
+				Expect(installProcess.ExecuteCall.CallCount).To(Equal(1))
+				Expect(sitePackageProcess.ExecuteCall.CallCount).To(Equal(1))
+
+				Expect(result.Layers[0].Metadata[pip.SitePackagesKey]).To(Equal("lib/python1.23/site-packages"))
+			})
+		})
+
+		context("when the layer was built without recording the site packages directory", func() {
+			it.Before(func() {
+				err := os.WriteFile(filepath.Join(layersDir, fmt.Sprintf("%s.toml", pip.Pip)), []byte(fmt.Sprintf(`[metadata]
+				%s = "some-sha"
+				%s = "1.23.4"
+				%s = "cpython-123-x86_64-linux-gnu"
+				%s = %q
+				%s = "user"
+				`, pip.DependencyChecksumKey, pip.PythonVersionKey, pip.PythonABIKey, pip.ArchitectureKey, runtime.GOARCH, pip.InstallModeKey)), os.ModePerm)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			it("rebuilds the layer", func() {
+				_, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).To(ContainSubstring("site packages directory was not recorded"))
+				Expect(installProcess.ExecuteCall.CallCount).To(Equal(1))
+			})
+		})
+
+		context("when the cached dependency sha does not match the selected dependency sha", func() {
+			it.Before(func() {
+				dependencyManager.ResolveCall.Returns.Dependency.Checksum = "other-sha"
+			})
+
+			it("rebuilds the layer", func() {
+				_, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).To(ContainSubstring(fmt.Sprintf("Rebuilding cached layer %s: pip dependency checksum changed", filepath.Join(layersDir, "pip"))))
+				Expect(installProcess.ExecuteCall.CallCount).To(Equal(1))
+			})
+		})
+
+		context("when the python version has changed", func() {
+			it.Before(func() {
+				interpreterProcess.ExecuteCall.Returns.Interpreter.Version = "1.24.0"
+			})
+
+			it("rebuilds the layer", func() {
+				result, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).To(ContainSubstring("Python version changed from 1.23.4 to 1.24.0"))
+				Expect(buffer.String()).To(ContainSubstring("Executing build process"))
+				Expect(installProcess.ExecuteCall.CallCount).To(Equal(1))
+
+				Expect(result.Layers[0].Metadata["python_version"]).To(Equal("1.24.0"))
+			})
+		})
+
+		context("when the python ABI has changed", func() {
+			it.Before(func() {
+				interpreterProcess.ExecuteCall.Returns.Interpreter.ABI = "cpython-123t-x86_64-linux-gnu"
+			})
+
+			it("rebuilds the layer", func() {
+				_, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).To(ContainSubstring("Python ABI changed from cpython-123-x86_64-linux-gnu to cpython-123t-x86_64-linux-gnu"))
+				Expect(installProcess.ExecuteCall.CallCount).To(Equal(1))
+			})
+		})
+
+		context("when the install mode has changed", func() {
+			it.Before(func() {
+				t.Setenv("BP_PIP_INSTALL_MODE", "venv")
+			})
+
+			it("rebuilds the layer", func() {
+				_, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).To(ContainSubstring("install mode changed from user to venv"))
+				Expect(installProcess.ExecuteCall.CallCount).To(Equal(1))
+			})
+		})
+
+		context("when the layer was built on another architecture", func() {
+			it.Before(func() {
+				err := os.WriteFile(filepath.Join(layersDir, fmt.Sprintf("%s.toml", pip.Pip)), []byte(fmt.Sprintf(`[metadata]
+				%s = "some-sha"
+				%s = "1.23.4"
+				%s = "cpython-123-x86_64-linux-gnu"
+				%s = "some-arch"
+				`, pip.DependencyChecksumKey, pip.PythonVersionKey, pip.PythonABIKey, pip.ArchitectureKey)), os.ModePerm)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			it("rebuilds the layer", func() {
+				_, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).To(ContainSubstring(fmt.Sprintf("architecture changed from some-arch to %s", runtime.GOARCH)))
+				Expect(installProcess.ExecuteCall.CallCount).To(Equal(1))
+			})
+		})
+
+		context("when the layer was built without recording the python interpreter", func() {
+			it.Before(func() {
+				err := os.WriteFile(filepath.Join(layersDir, fmt.Sprintf("%s.toml", pip.Pip)), []byte(fmt.Sprintf(`[metadata]
+				%s = "some-sha"
+				`, pip.DependencyChecksumKey)), os.ModePerm)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			it("rebuilds the layer", func() {
+				_, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).To(ContainSubstring("Python version changed from <unknown> to 1.23.4"))
+				Expect(installProcess.ExecuteCall.CallCount).To(Equal(1))
+			})
+		})
+	})
+
+	context("when the selected dependency has a deprecation date", func() {
+		context("when the deprecation date is within the warning window", func() {
+			it.Before(func() {
+				dependencyManager.ResolveCall.Returns.Dependency.DeprecationDate = time.Now().Add(10*24*time.Hour + time.Hour)
+			})
+
+			it("logs a warning", func() {
+				_, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).To(ContainLines(
+					MatchRegexp(`  Warning: pip 21.0 will be deprecated on \d{4}-\d{2}-\d{2} \(in 11 day\(s\)\)`),
+					"    Migrate your application to a supported version of pip before this time.",
+				))
+				Expect(buffer.String()).NotTo(ContainSubstring("Version 21.0 of Pip will be deprecated"))
+			})
+
+			context("when BP_PIP_DEPRECATION_WINDOW_DAYS is smaller", func() {
+				it.Before(func() {
+					t.Setenv("BP_PIP_DEPRECATION_WINDOW_DAYS", "7")
+				})
+
+				it("does not log a warning", func() {
+					_, err := build(buildContext)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(buffer.String()).NotTo(ContainSubstring("deprecated"))
+				})
+			})
+
+			context("when BP_PIP_FAIL_ON_DEPRECATED is true", func() {
+				it.Before(func() {
+					t.Setenv("BP_PIP_FAIL_ON_DEPRECATED", "true")
+				})
+
+				it("only logs a warning", func() {
+					_, err := build(buildContext)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(buffer.String()).To(ContainSubstring("Warning: pip 21.0 will be deprecated on"))
+				})
+			})
+		})
+
+		context("when the deprecation date has passed", func() {
+			var deprecationDate time.Time
+
+			it.Before(func() {
+				deprecationDate = time.Now().Add(-24 * time.Hour)
+				dependencyManager.ResolveCall.Returns.Dependency.DeprecationDate = deprecationDate
+			})
+
+			it("logs a warning", func() {
+				_, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).To(ContainLines(
+					fmt.Sprintf("  Warning: pip 21.0 was deprecated on %s", deprecationDate.Format("2006-01-02")),
+					"    Migrate your application to a supported version of pip.",
+				))
+			})
+
+			context("when BP_PIP_FAIL_ON_DEPRECATED is true", func() {
+				it.Before(func() {
+					t.Setenv("BP_PIP_FAIL_ON_DEPRECATED", "true")
+				})
+
+				it("returns an error", func() {
+					_, err := build(buildContext)
+					Expect(err).To(MatchError(fmt.Sprintf("pip 21.0 was deprecated on %s and BP_PIP_FAIL_ON_DEPRECATED is set: migrate your application to a supported version of pip", deprecationDate.Format("2006-01-02"))))
+					Expect(installProcess.ExecuteCall.CallCount).To(Equal(0))
+				})
+			})
+
+			context("when BP_PIP_FAIL_ON_DEPRECATED cannot be parsed", func() {
+				it.Before(func() {
+					t.Setenv("BP_PIP_FAIL_ON_DEPRECATED", "some-value")
+				})
+
+				it("returns an error", func() {
+					_, err := build(buildContext)
+					Expect(err).To(MatchError(ContainSubstring(`failed to parse BP_PIP_FAIL_ON_DEPRECATED value "some-value"`)))
+				})
+			})
+		})
+
+		context("when BP_PIP_DEPRECATION_WINDOW_DAYS cannot be parsed", func() {
+			it.Before(func() {
+				t.Setenv("BP_PIP_DEPRECATION_WINDOW_DAYS", "a month")
+			})
+
+			it("returns an error", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError(`failed to parse BP_PIP_DEPRECATION_WINDOW_DAYS value "a month": expected a non-negative number of days`))
+			})
+		})
+	})
+
+	context("failure cases", func() {
+		context("when dependency resolution fails", func() {
+			it.Before(func() {
+				dependencyManager.ResolveCall.Returns.Error = errors.New("failed to resolve dependency")
+			})
+			it("returns an error", func() {
+				_, err := build(buildContext)
+
+				Expect(err).To(MatchError(ContainSubstring("failed to resolve dependency")))
+			})
+
+			context("when other versions of pip are available", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(filepath.Join(cnbDir, "buildpack.toml"), []byte(`
+[[metadata.dependencies]]
+  id = "pip"
+  stacks = ["*"]
+  version = "24.0.0"
+
+[[metadata.dependencies]]
+  id = "pip"
+  stacks = ["some-stack"]
+  version = "23.3.2"
+
+[[metadata.dependencies]]
+  id = "pip"
+  stacks = ["some-stack"]
+  version = "23.2.1"
+
+[[metadata.dependencies]]
+  id = "pip"
+  stacks = ["other-stack"]
+  version = "23.4.0"
+`), 0600)).To(Succeed())
+
+					buildContext.Plan.Entries[0].Metadata = map[string]interface{}{
+						"version-source": "BP_PIP_VERSION",
+						"version":        "23.5.*",
+					}
+				})
+
+				it("lists the available versions and suggests the closest one", func() {
+					_, err := build(buildContext)
+					Expect(err).To(MatchError(`failed to resolve pip version "23.5.*" requested by BP_PIP_VERSION: available versions on stack "some-stack" that support Python 1.23.4 are 24.0.0, 23.3.2, 23.2.1; the closest match is 23.3.2 (set BP_PIP_VERSION=23.3.2): failed to resolve dependency`))
+				})
+
+				context("when the version was requested without a version source", func() {
+					it.Before(func() {
+						buildContext.Plan.Entries[0].Metadata = map[string]interface{}{
+							"version": "~> 30",
+						}
+					})
+
+					it("suggests the closest version", func() {
+						_, err := build(buildContext)
+						Expect(err).To(MatchError(ContainSubstring(`failed to resolve pip version "~> 30" requested by <unknown>`)))
+						Expect(err).To(MatchError(ContainSubstring("the closest match is 24.0.0 (use 24.0.0)")))
+					})
+				})
+
+				context("when some of the available versions do not support the python version", func() {
+					it.Before(func() {
+						Expect(os.WriteFile(filepath.Join(cnbDir, "buildpack.toml"), []byte(`
+[[metadata.dependencies]]
+  id = "pip"
+  stacks = ["*"]
+  version = "24.0.0"
+
+[[metadata.dependencies]]
+  id = "pip"
+  requires-python = ">=1.30"
+  stacks = ["some-stack"]
+  version = "23.3.2"
+
+[[metadata.dependencies]]
+  id = "pip"
+  stacks = ["some-stack"]
+  version = "23.2.1"
+`), 0600)).To(Succeed())
+					})
+
+					it("only lists and suggests the versions that support it", func() {
+						_, err := build(buildContext)
+						Expect(err).To(MatchError(`failed to resolve pip version "23.5.*" requested by BP_PIP_VERSION: available versions on stack "some-stack" that support Python 1.23.4 are 24.0.0, 23.2.1; the closest match is 23.2.1 (set BP_PIP_VERSION=23.2.1): failed to resolve dependency`))
+					})
+				})
+			})
+
+			context("when no versions of pip are available on the stack", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(filepath.Join(cnbDir, "buildpack.toml"), []byte(`
+[[metadata.dependencies]]
+  id = "pip"
+  stacks = ["other-stack"]
+  version = "24.0.0"
+`), 0600)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := build(buildContext)
+					Expect(err).To(MatchError(`failed to resolve pip version "default" requested by <unknown>: no versions of pip that support Python 1.23.4 are available on stack "some-stack": failed to resolve dependency`))
+				})
+			})
+		})
+
+		context("when the python interpreter cannot be inspected", func() {
+			it.Before(func() {
+				interpreterProcess.ExecuteCall.Returns.Error = errors.New("failed to inspect python interpreter")
+			})
+
+			it("returns an error", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError("failed to inspect python interpreter"))
+			})
+		})
+
+		context("when the buildpack.toml cannot be parsed", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(cnbDir, "buildpack.toml"), []byte("%%%"), 0600)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError(ContainSubstring("failed to parse buildpack.toml")))
+			})
+		})
+
+		context("when the requested version does not support the python interpreter", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(cnbDir, "buildpack.toml"), []byte(`
+[[metadata.dependencies]]
+  id = "pip"
+  requires-python = ">=1.30"
+  stacks = ["*"]
+  version = "23.0.0"
+`), 0600)).To(Succeed())
+
+				buildContext.Plan.Entries[0].Metadata = map[string]interface{}{
+					"version-source": "BP_PIP_VERSION",
+					"version":        "23.0.0",
+				}
+			})
+
+			it("returns an error naming both versions", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError(`pip 23.0.0 requires Python >=1.30, which is not compatible with the installed Python 1.23.4 (pip version "23.0.0" requested by BP_PIP_VERSION)`))
+			})
+		})
+
+		context("when the requires-python of a dependency cannot be parsed", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(cnbDir, "buildpack.toml"), []byte(`
+[[metadata.dependencies]]
+  id = "pip"
+  requires-python = "not-a-specifier"
+  stacks = ["*"]
+  version = "23.0.0"
+`), 0600)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError(ContainSubstring(`failed to parse requires-python "not-a-specifier" of pip 23.0.0`)))
+			})
+		})
+
+		context("when BP_PIP_INSTALL_MODE is not supported", func() {
+			it.Before(func() {
+				t.Setenv("BP_PIP_INSTALL_MODE", "some-mode")
+			})
+
+			it("returns an error", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError(`unsupported BP_PIP_INSTALL_MODE "some-mode": expected "user" or "venv"`))
+			})
+		})
+
+		context("when the bindings cannot be resolved", func() {
+			it.Before(func() {
+				bindingResolver.ResolveCall.Stub = nil
+				bindingResolver.ResolveCall.Returns.Error = errors.New("failed to resolve bindings")
+			})
+
+			it("returns an error", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError("failed to resolve bindings"))
+			})
+		})
+
+		context("when there is more than one pip binding", func() {
+			it.Before(func() {
+				bindings["pip"] = []servicebindings.Binding{
+					{Name: "some-binding", Type: "pip"},
+					{Name: "other-binding", Type: "pip"},
+				}
+			})
+
+			it("returns an error", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError("binding resolver found more than one binding of type 'pip'"))
+			})
+		})
+
+		context("when the pip binding does not contain a pip.conf", func() {
+			it.Before(func() {
+				bindings["pip"] = []servicebindings.Binding{
+					{
+						Name: "some-binding",
+						Path: "/bindings/some-binding",
+						Type: "pip",
+						Entries: map[string]*servicebindings.Entry{
+							".netrc": servicebindings.NewEntry("/bindings/some-binding/.netrc"),
+						},
+					},
+				}
+			})
+
+			it("returns an error", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError("binding of type 'pip' is missing required entry 'pip.conf'"))
+			})
+		})
+
+		context("when pip layer cannot be fetched", func() {
+			it.Before(func() {
+				Expect(os.Chmod(layersDir, 0000)).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Chmod(layersDir, os.ModePerm)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := build(buildContext)
+
+				Expect(err).To(MatchError(ContainSubstring("permission denied")))
+			})
+		})
+
+		context("when pip layer cannot be reset", func() {
+			it.Before(func() {
+				Expect(os.MkdirAll(filepath.Join(layersDir, pip.Pip), os.ModePerm))
+				Expect(os.Chmod(layersDir, 0500)).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Chmod(layersDir, os.ModePerm)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := build(buildContext)
+
+				Expect(err).To(MatchError(ContainSubstring("permission denied")))
+			})
+		})
+
+		context("when dependency cannot be installed", func() {
+			it.Before(func() {
+				dependencyManager.DeliverCall.Returns.Error = errors.New("failed to install dependency")
+			})
+			it("returns an error", func() {
+				_, err := build(buildContext)
+
+				Expect(err).To(MatchError(ContainSubstring("failed to install dependency")))
+			})
+		})
+
+		context("when pip cannot be installed", func() {
+			it.Before(func() {
+				installProcess.ExecuteCall.Stub = nil
+				installProcess.ExecuteCall.Returns.Error = errors.New("failed to configure pip")
+			})
+
+			it("returns an error", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError("failed to configure pip"))
+				Expect(buffer.String()).NotTo(ContainSubstring("Failed to install pip"))
+			})
+
+			context("when the cause of the failure is recognized", func() {
+				it.Before(func() {
+					installProcess.ExecuteCall.Returns.Error = pip.InstallError{
+						Action:      "configure pip",
+						Cause:       pip.ErrTLS,
+						Remediation: "Provide the CA certificates of your network with a binding of type ca-certificates.",
+						Output:      "some-output",
+						Err:         errors.New("exit status 1"),
+					}
+				})
+
+				it("logs a remediation hint and returns the error", func() {
+					_, err := build(buildContext)
+					Expect(err).To(MatchError(pip.ErrTLS))
+
+					Expect(buffer.String()).To(ContainLines(
+						"      Failed to install pip: TLS certificate verification failed",
+						"        Provide the CA certificates of your network with a binding of type ca-certificates.",
+					))
+				})
+			})
+		})
+
+		context("when the installed pip cannot be run", func() {
+			it.Before(func() {
+				versionProcess.ExecuteCall.Returns.Error = errors.New("failed to run pip")
+			})
+
+			it("returns an error", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError("failed to verify pip installation: failed to run pip"))
+			})
+		})
+
+		context("when the installed pip reports a different version", func() {
+			it.Before(func() {
+				versionProcess.ExecuteCall.Returns.String = "20.3.4"
+			})
+
+			it("returns an error naming both versions", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError("failed to verify pip installation: expected pip 21.0 but the installed pip reports 20.3.4"))
+			})
+		})
+
+		context("when the site packages cannot be found", func() {
+			it.Before(func() {
+				sitePackageProcess.ExecuteCall.Returns.Error = errors.New("failed to find site-packages dir")
+			})
+
+			it("returns an error", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError(ContainSubstring("failed to find site-packages dir")))
+			})
+		})
+
+		context("when the layer does not have a site-packages directory", func() {
+			it.Before(func() {
+				sitePackageProcess.ExecuteCall.Returns.String = ""
+			})
+
+			it("returns an error", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError(ContainSubstring("pip installation failed: site packages are missing from the pip layer")))
+			})
+		})
+
+		context("when generating the SBOM returns an error", func() {
+			it.Before(func() {
+				buildContext.BuildpackInfo.SBOMFormats = []string{"random-format"}
+			})
+
+			it("returns an error", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError(`unsupported SBOM format: 'random-format'`))
+			})
+		})
+
+		context("when formatting the SBOM returns an error", func() {
+			it.Before(func() {
+				sbomGenerator.GenerateFromDependenciesCall.Returns.Error = errors.New("failed to generate SBOM")
+			})
+
+			it("returns an error", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError(ContainSubstring("failed to generate SBOM")))
+			})
+		})
+	})
+}
+
+func writeWheel(t *testing.T, path, name, content string) {
+	file, err := os.Create(path)
+	NewWithT(t).Expect(err).NotTo(HaveOccurred())
+	defer file.Close()
+
+	writer := zip.NewWriter(file)
+	entry, err := writer.Create(name)
+	NewWithT(t).Expect(err).NotTo(HaveOccurred())
+
+	_, err = entry.Write([]byte(content))
+	NewWithT(t).Expect(err).NotTo(HaveOccurred())
+	NewWithT(t).Expect(writer.Close()).To(Succeed())
+}
+
+func writeSdist(t *testing.T, path, name, content string) {
+	file, err := os.Create(path)
+	NewWithT(t).Expect(err).NotTo(HaveOccurred())
+	defer file.Close()
+
+	gzipWriter := gzip.NewWriter(file)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	NewWithT(t).Expect(tarWriter.WriteHeader(&tar.Header{
+		Name:     name,
+		Mode:     0644,
+		Size:     int64(len(content)),
+		Typeflag: tar.TypeReg,
+	})).To(Succeed())
+
+	_, err = tarWriter.Write([]byte(content))
+	NewWithT(t).Expect(err).NotTo(HaveOccurred())
+	NewWithT(t).Expect(tarWriter.Close()).To(Succeed())
+	NewWithT(t).Expect(gzipWriter.Close()).To(Succeed())
 }
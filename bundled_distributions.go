@@ -0,0 +1,227 @@
+package pip
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2/postal"
+)
+
+// bundledDependencies returns the distributions bundled with pip in the
+// pip-source layer at the given path (e.g. setuptools, wheel and the build
+// requirements of pip), described by the metadata in their wheels and sdists.
+// Distributions of pip itself are omitted.
+func bundledDependencies(srcPath string) ([]postal.Dependency, error) {
+	entries, err := os.ReadDir(srcPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list bundled distributions: %w", err)
+	}
+
+	seen := map[string]bool{}
+	var dependencies []postal.Dependency
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		metadata, err := distributionMetadata(filepath.Join(srcPath, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read metadata of bundled distribution %s: %w", entry.Name(), err)
+		}
+
+		if metadata == nil {
+			continue
+		}
+
+		name := distributionName(metadata)
+		version := metadata.Get("Version")
+		if name == "" || name == Pip || seen[name+"@"+version] {
+			continue
+		}
+		seen[name+"@"+version] = true
+
+		dependency := postal.Dependency{
+			ID:      name,
+			Name:    name,
+			Version: version,
+			PURL:    fmt.Sprintf("pkg:pypi/%s@%s", name, version),
+		}
+
+		if license := distributionLicense(metadata); license != "" {
+			dependency.Licenses = []string{license}
+		}
+
+		dependencies = append(dependencies, dependency)
+	}
+
+	sort.Slice(dependencies, func(i, j int) bool {
+		if dependencies[i].Name == dependencies[j].Name {
+			return dependencies[i].Version < dependencies[j].Version
+		}
+		return dependencies[i].Name < dependencies[j].Name
+	})
+
+	return dependencies, nil
+}
+
+// distributionMetadata reads the core metadata of the wheel or sdist at the
+// given path. It returns nil when the file is not a distribution.
+func distributionMetadata(archivePath string) (textproto.MIMEHeader, error) {
+	switch {
+	case strings.HasSuffix(archivePath, ".whl"):
+		return readZipMetadata(archivePath, func(name string) bool {
+			dir, file := path.Split(name)
+			return file == "METADATA" && strings.Count(dir, "/") == 1 && strings.HasSuffix(dir, ".dist-info/")
+		})
+
+	case strings.HasSuffix(archivePath, ".zip"):
+		return readZipMetadata(archivePath, isSdistMetadata)
+
+	case strings.HasSuffix(archivePath, ".tar.gz"):
+		return readTarMetadata(archivePath, isSdistMetadata)
+	}
+
+	return nil, nil
+}
+
+// distributionName returns the normalized project name from the given core
+// metadata.
+func distributionName(metadata textproto.MIMEHeader) string {
+	return strings.ToLower(separatorPattern.ReplaceAllString(metadata.Get("Name"), "-"))
+}
+
+// isSdistMetadata matches the PKG-INFO file at the root of an sdist.
+func isSdistMetadata(name string) bool {
+	dir, file := path.Split(strings.TrimPrefix(name, "./"))
+	return file == "PKG-INFO" && strings.Count(dir, "/") == 1
+}
+
+func readZipMetadata(archivePath string, match func(string) bool) (textproto.MIMEHeader, error) {
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	for _, file := range reader.File {
+		if !match(file.Name) {
+			continue
+		}
+
+		content, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer content.Close()
+
+		return parseDistributionMetadata(content)
+	}
+
+	return nil, nil
+}
+
+func readTarMetadata(archivePath string, match func(string) bool) (textproto.MIMEHeader, error) {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	gzipReader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer gzipReader.Close()
+
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if header.Typeflag == tar.TypeReg && match(header.Name) {
+			return parseDistributionMetadata(tarReader)
+		}
+	}
+}
+
+// parseDistributionMetadata parses the headers of a core metadata file
+// (PKG-INFO or METADATA), which use the RFC 822 format.
+func parseDistributionMetadata(reader io.Reader) (textproto.MIMEHeader, error) {
+	header, err := textproto.NewReader(bufio.NewReader(reader)).ReadMIMEHeader()
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	return header, nil
+}
+
+// distributionLicense returns the license of a distribution, preferring the
+// SPDX License-Expression, then a short License field, then the license
+// classifiers.
+func distributionLicense(metadata textproto.MIMEHeader) string {
+	if expression := strings.TrimSpace(metadata.Get("License-Expression")); expression != "" {
+		return expression
+	}
+
+	// The License field sometimes contains the full text of the license,
+	// which is not useful as a license identifier.
+	license := strings.TrimSpace(metadata.Get("License"))
+	if license != "" && license != "UNKNOWN" && len(license) <= 64 {
+		return license
+	}
+
+	var classifiers []string
+	for _, classifier := range metadata["Classifier"] {
+		if strings.HasPrefix(classifier, "License ::") {
+			parts := strings.Split(classifier, "::")
+			classifiers = append(classifiers, strings.TrimSpace(parts[len(parts)-1]))
+		}
+	}
+
+	return strings.Join(classifiers, " AND ")
+}
+
+// writeConstraints writes a pip constraints file to the given path that pins
+// each of the given dependencies to its version. Dependencies that are
+// bundled in more than one version are left unconstrained.
+func writeConstraints(path string, dependencies []postal.Dependency) error {
+	versions := map[string][]string{}
+	var names []string
+	for _, dependency := range dependencies {
+		if _, ok := versions[dependency.Name]; !ok {
+			names = append(names, dependency.Name)
+		}
+		versions[dependency.Name] = append(versions[dependency.Name], dependency.Version)
+	}
+	sort.Strings(names)
+
+	var constraints strings.Builder
+	for _, name := range names {
+		if len(versions[name]) == 1 {
+			fmt.Fprintf(&constraints, "%s==%s\n", name, versions[name][0])
+		}
+	}
+
+	err := os.WriteFile(path, []byte(constraints.String()), 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write pip constraints: %w", err)
+	}
+
+	return nil
+}
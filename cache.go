@@ -0,0 +1,91 @@
+package pip
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+type cacheFile struct {
+	path    string
+	size    uint64
+	modTime time.Time
+}
+
+// cacheMaxSize returns the size limit of the pip cache layer, which can be
+// configured with $BP_PIP_CACHE_MAX_SIZE (e.g. "500MiB" or "2GB").
+func cacheMaxSize() (uint64, error) {
+	value, ok := os.LookupEnv("BP_PIP_CACHE_MAX_SIZE")
+	if !ok || value == "" {
+		value = DefaultPipCacheMaxSize
+	}
+
+	size, err := humanize.ParseBytes(value)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse BP_PIP_CACHE_MAX_SIZE: %w", err)
+	}
+
+	return size, nil
+}
+
+// pruneCache removes the least recently modified files from the cache
+// directory at the given path until its total size no longer exceeds
+// maxSize. It returns the size of the cache before and after pruning.
+func pruneCache(path string, maxSize uint64) (uint64, uint64, error) {
+	var (
+		files []cacheFile
+		size  uint64
+	)
+
+	err := filepath.WalkDir(path, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		files = append(files, cacheFile{path: path, size: uint64(info.Size()), modTime: info.ModTime()})
+		size += uint64(info.Size())
+
+		return nil
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to measure pip cache: %w", err)
+	}
+
+	if size <= maxSize {
+		return size, size, nil
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+
+	pruned := size
+	for _, file := range files {
+		if pruned <= maxSize {
+			break
+		}
+
+		err = os.Remove(file.path)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to prune pip cache: %w", err)
+		}
+
+		pruned -= file.size
+	}
+
+	return size, pruned, nil
+}
@@ -5,12 +5,147 @@ const Pip = "pip"
 
 const PipSrc = "pip-source"
 
+// PipCache is the name of the layer that persists the HTTP and wheel cache of
+// invocations of pip in downstream buildpacks across builds.
+const PipCache = "pip-cache"
+
+// DefaultPipCacheMaxSize is the size above which the pip cache layer is
+// pruned, unless overridden by $BP_PIP_CACHE_MAX_SIZE.
+const DefaultPipCacheMaxSize = "1GiB"
+
+// PipWheelPattern matches the prebuilt pip wheel that is bundled next to the
+// pip source code in the pip dependency.
+const PipWheelPattern = "pip-*-py3-none-any.whl"
+
+// ExternallyManagedFile is the name of the marker file in the standard
+// library directory of an externally managed Python interpreter (PEP 668).
+const ExternallyManagedFile = "EXTERNALLY-MANAGED"
+
+// UserInstallMode and VenvInstallMode are the supported values of
+// $BP_PIP_INSTALL_MODE. In user mode, pip is installed into the user base of
+// the pip layer. In venv mode, pip is installed into a virtual environment in
+// the pip layer.
+const (
+	UserInstallMode = "user"
+	VenvInstallMode = "venv"
+)
+
+// VenvConfigFile is the file that marks the root of a virtual environment.
+const VenvConfigFile = "pyvenv.cfg"
+
+// PipSdistPattern and PipZipSdistPattern match a pip sdist, which is
+// installed when neither a prebuilt pip wheel nor the pip source code is
+// available.
+const (
+	PipSdistPattern    = "pip-*.tar.gz"
+	PipZipSdistPattern = "pip-*.zip"
+)
+
+// PipConfig is the name of the build-only layer that configures invocations
+// of pip in downstream buildpacks from service bindings.
+const PipConfig = "pip-config"
+
+// PipBindingType is the type of the service binding that provides pip
+// configuration.
+const PipBindingType = "pip"
+
+// PipConfigFile is the name of the pip configuration file entry in a service
+// binding of type pip.
+const PipConfigFile = "pip.conf"
+
+// CACertificatesBindingType is the type of the service bindings that provide
+// additional CA certificates for pip.
+const CACertificatesBindingType = "ca-certificates"
+
+// AdvisoriesBindingType is the type of the service bindings that provide
+// vulnerability advisories, in OSV JSON format, for pip and the distributions
+// bundled with it.
+const AdvisoriesBindingType = "pip-advisories"
+
+// WarnVulnerabilityPolicy and FailVulnerabilityPolicy are the supported values
+// of $BP_PIP_VULN_POLICY.
+const (
+	WarnVulnerabilityPolicy = "warn"
+	FailVulnerabilityPolicy = "fail"
+)
+
+// CABundleFile is the name of the CA certificate bundle in the pip-config
+// layer.
+const CABundleFile = "ca-bundle.pem"
+
+// SystemCABundle is the location of the CA certificates of the stack, unless
+// overridden by $SSL_CERT_FILE.
+const SystemCABundle = "/etc/ssl/certs/ca-certificates.crt"
+
+// NetrcFile is the name of the optional .netrc credentials entry in a service
+// binding of type pip.
+const NetrcFile = ".netrc"
+
 // CPython is the name of the python runtime dependency provided by the CPython buildpack: https://github.com/paketo-buildpacks/cpython
 const CPython = "cpython"
 
-// DependencyChecksumKey is the name of the key in the pip layer TOML whose value is pip dependency's SHA256.
+// DependencyChecksumKey is the name of the key in the pip layer TOML whose value is pip dependency's SHA256
+// (or the SHA256 of the distribution provided through $BP_PIP_SOURCE).
 const DependencyChecksumKey = "dependency_checksum"
 
+// PythonVersionKey is the name of the key in the pip layer TOML whose value is
+// the version of the python interpreter pip was installed for.
+const PythonVersionKey = "python_version"
+
+// PythonABIKey is the name of the key in the pip layer TOML whose value is the
+// ABI tag of the python interpreter pip was installed for.
+const PythonABIKey = "python_abi"
+
+// ArchitectureKey is the name of the key in the pip layer TOML whose value is
+// the architecture the layer was built on.
+const ArchitectureKey = "arch"
+
+// InstallModeKey is the name of the key in the pip layer TOML whose value is
+// the mode pip was installed in.
+const InstallModeKey = "install_mode"
+
+// SitePackagesKey is the name of the key in the pip layer TOML whose value is
+// the path of the site packages directory pip was installed to, relative to
+// the layer, in user install mode.
+const SitePackagesKey = "site_packages"
+
+// ExtraFindLinksKey is the name of the key in the pip layer TOML whose value
+// is the digest of the distributions added through $BP_PIP_EXTRA_FIND_LINKS.
+const ExtraFindLinksKey = "extra_find_links_digest"
+
+// ExtraFindLinksDir is the name of the directory in the pip-source layer that
+// holds the distributions added through $BP_PIP_EXTRA_FIND_LINKS.
+const ExtraFindLinksDir = "extra-find-links"
+
+// ConstraintsKey is the name of the key in the pip layer TOML whose value
+// records whether $BP_PIP_DISABLE_CONSTRAINTS disabled the constraints file.
+const ConstraintsKey = "constraints"
+
+// ConstraintsFile is the name of the pip constraints file in the pip-source
+// layer that pins the bundled distributions.
+const ConstraintsFile = "constraints.txt"
+
+// SystemPipVersionKey is the name of the key in the pip layer TOML whose value
+// is the version of the pip bundled with the python interpreter, when it was
+// adopted instead of installing pip.
+const SystemPipVersionKey = "system_pip_version"
+
+// PyProjectFile is the name of the file in the application source in which a
+// version of pip can be requested.
+const PyProjectFile = "pyproject.toml"
+
+// PipVersionFile is the name of the file in the application source that can
+// be used to pin the version of pip.
+const PipVersionFile = ".pip-version"
+
 // Priorities is a list of possible places where the buildpack could look for a
 // specific version of Pip to install, ordered from highest to lowest priority.
-var Priorities = []interface{}{"BP_PIP_VERSION"}
+var Priorities = []interface{}{"BP_PIP_VERSION", PipVersionFile, PyProjectFile}
+
+// LatestVersion and SystemVersion are the keywords that can be used in place
+// of a version of pip: LatestVersion selects the newest version of pip, and
+// SystemVersion the pip bundled with the python interpreter.
+const (
+	LatestVersion = "latest"
+	SystemVersion = "system"
+)
@@ -0,0 +1,169 @@
+// Command deprecation sets the deprecation_date field of each pip dependency
+// in a buildpack.toml according to the support policy for versions of pip:
+// a minor version is deprecated a number of months after the first release
+// of a newer minor version.
+//
+// The policy depends on releases made after a version was added, so the date
+// of every dependency is recomputed after each update of the dependencies.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/Masterminds/semver/v3"
+	"github.com/paketo-buildpacks/libdependency/upstream"
+)
+
+type PyPiProductMetadataRaw struct {
+	Releases map[string][]struct {
+		PackageType string `json:"packagetype"`
+		UploadTime  string `json:"upload_time_iso_8601"`
+	} `json:"releases"`
+}
+
+type PyPiRelease struct {
+	version    *semver.Version
+	UploadTime time.Time
+}
+
+func main() {
+	var (
+		buildpackTomlPath string
+		supportMonths     int
+	)
+	flag.StringVar(&buildpackTomlPath, "buildpack-toml-path", "", "path to the buildpack.toml file to update")
+	flag.IntVar(&supportMonths, "support-months", 12, "number of months a minor version of pip is supported after a newer minor version is released, or 0 to omit deprecation dates")
+	flag.Parse()
+
+	if buildpackTomlPath == "" {
+		log.Fatal("missing required flag --buildpack-toml-path")
+	}
+
+	var pypiMetadata PyPiProductMetadataRaw
+	err := upstream.GetAndUnmarshal("https://pypi.org/pypi/pip/json", &pypiMetadata)
+	if err != nil {
+		log.Fatal(fmt.Errorf("could not retrieve releases from upstream: %w", err))
+	}
+
+	releases, err := pipReleases(pypiMetadata)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = setDeprecationDates(buildpackTomlPath, releases, supportMonths)
+	if err != nil {
+		log.Fatal(fmt.Errorf("could not set deprecation dates in %s: %w", buildpackTomlPath, err))
+	}
+}
+
+// pipReleases returns the releases of pip with a semver version and an sdist,
+// along with the upload time of the sdist.
+func pipReleases(pypiMetadata PyPiProductMetadataRaw) ([]PyPiRelease, error) {
+	var releases []PyPiRelease
+	for version, releasesForVersion := range pypiMetadata.Releases {
+		semverVersion, err := semver.NewVersion(version)
+		if err != nil {
+			continue
+		}
+
+		for _, release := range releasesForVersion {
+			if release.PackageType != "sdist" {
+				continue
+			}
+
+			uploadTime, err := time.Parse(time.RFC3339, release.UploadTime)
+			if err != nil {
+				return nil, fmt.Errorf("could not parse upload time '%s' as date for version %s: %w", release.UploadTime, version, err)
+			}
+
+			releases = append(releases, PyPiRelease{version: semverVersion, UploadTime: uploadTime})
+		}
+	}
+
+	return releases, nil
+}
+
+// setDeprecationDates sets the deprecation_date field of each pip dependency
+// in the buildpack.toml at the given path from the given releases. The field
+// is removed from dependencies that have not been superseded yet. The file is
+// encoded in the same way as jam writes it.
+func setDeprecationDates(path string, releases []PyPiRelease, months int) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	var config map[string]interface{}
+	_, err = toml.DecodeFile(path, &config)
+	if err != nil {
+		return err
+	}
+
+	metadata, _ := config["metadata"].(map[string]interface{})
+	dependencies, _ := metadata["dependencies"].([]map[string]interface{})
+	for _, dependency := range dependencies {
+		id, _ := dependency["id"].(string)
+		version, _ := dependency["version"].(string)
+		if id != "pip" {
+			continue
+		}
+
+		semverVersion, err := semver.NewVersion(version)
+		if err != nil {
+			return fmt.Errorf("could not parse version %q: %w", version, err)
+		}
+
+		date := deprecationDate(semverVersion, releases, months)
+		if date == nil {
+			delete(dependency, "deprecation_date")
+			continue
+		}
+
+		dependency["deprecation_date"] = *date
+	}
+
+	buffer := bytes.NewBuffer(nil)
+	err = toml.NewEncoder(buffer).Encode(config)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, buffer.Bytes(), info.Mode().Perm())
+}
+
+// deprecationDate returns the date on which the given version of pip is
+// deprecated: the given number of months after the first release of a newer
+// minor (or major) version. It returns nil while no newer minor version has
+// been released, or when months is not positive.
+func deprecationDate(version *semver.Version, releases []PyPiRelease, months int) *time.Time {
+	if months <= 0 {
+		return nil
+	}
+
+	var superseded *time.Time
+	for _, release := range releases {
+		newer := release.version.Major() > version.Major() ||
+			(release.version.Major() == version.Major() && release.version.Minor() > version.Minor())
+		if !newer || release.version.Prerelease() != "" {
+			continue
+		}
+
+		if superseded == nil || release.UploadTime.Before(*superseded) {
+			uploadTime := release.UploadTime
+			superseded = &uploadTime
+		}
+	}
+
+	if superseded == nil {
+		return nil
+	}
+
+	date := superseded.AddDate(0, months, 0).UTC().Truncate(24 * time.Hour)
+	return &date
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestDeprecation(t *testing.T) {
+	spec.Run(t, "deprecation", testDeprecation, spec.Report(report.Terminal{}))
+}
+
+func testDeprecation(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		path     string
+		releases []PyPiRelease
+	)
+
+	release := func(version, uploadTime string) PyPiRelease {
+		upload, err := time.Parse(time.RFC3339, uploadTime)
+		Expect(err).NotTo(HaveOccurred())
+
+		return PyPiRelease{version: semver.MustParse(version), UploadTime: upload}
+	}
+
+	it.Before(func() {
+		releases = []PyPiRelease{
+			release("23.3.0", "2023-10-15T10:00:00Z"),
+			release("23.3.2", "2023-12-17T10:00:00Z"),
+			release("24.0.0", "2024-02-03T12:30:00Z"),
+			release("24.1.0", "2024-06-20T10:00:00Z"),
+		}
+
+		path = filepath.Join(t.TempDir(), "buildpack.toml")
+		Expect(os.WriteFile(path, []byte(`api = "0.7"
+
+[metadata]
+
+  [[metadata.dependencies]]
+    id = "pip"
+    stacks = ["*"]
+    version = "23.3.2"
+
+  [[metadata.dependencies]]
+    deprecation_date = 2024-01-01T00:00:00Z
+    id = "pip"
+    stacks = ["*"]
+    version = "24.1.0"
+
+  [[metadata.dependencies]]
+    id = "other"
+    stacks = ["*"]
+    version = "23.3.2"
+`), 0644)).To(Succeed())
+	})
+
+	context("pipReleases", func() {
+		it("returns the semver releases with the upload time of their sdist", func() {
+			var metadata PyPiProductMetadataRaw
+			metadata.Releases = map[string][]struct {
+				PackageType string `json:"packagetype"`
+				UploadTime  string `json:"upload_time_iso_8601"`
+			}{
+				"24.0": {
+					{PackageType: "bdist_wheel", UploadTime: "2024-02-03T12:00:00Z"},
+					{PackageType: "sdist", UploadTime: "2024-02-03T12:30:00Z"},
+				},
+				"not-semver!": {{PackageType: "sdist", UploadTime: "2024-02-03T12:30:00Z"}},
+			}
+
+			Expect(pipReleases(metadata)).To(Equal([]PyPiRelease{
+				release("24.0", "2024-02-03T12:30:00Z"),
+			}))
+		})
+
+		context("failure cases", func() {
+			context("when the upload time cannot be parsed", func() {
+				it("returns an error", func() {
+					var metadata PyPiProductMetadataRaw
+					metadata.Releases = map[string][]struct {
+						PackageType string `json:"packagetype"`
+						UploadTime  string `json:"upload_time_iso_8601"`
+					}{
+						"24.0": {{PackageType: "sdist", UploadTime: "yesterday"}},
+					}
+
+					_, err := pipReleases(metadata)
+					Expect(err).To(MatchError(ContainSubstring("could not parse upload time 'yesterday' as date for version 24.0")))
+				})
+			})
+		})
+	})
+
+	context("setDeprecationDates", func() {
+		it("sets the deprecation date of the pip dependencies only", func() {
+			Expect(setDeprecationDates(path, releases, 12)).To(Succeed())
+
+			content, err := os.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(Equal(`api = "0.7"
+
+[metadata]
+
+  [[metadata.dependencies]]
+    deprecation_date = 2025-02-03T00:00:00Z
+    id = "pip"
+    stacks = ["*"]
+    version = "23.3.2"
+
+  [[metadata.dependencies]]
+    id = "pip"
+    stacks = ["*"]
+    version = "24.1.0"
+
+  [[metadata.dependencies]]
+    id = "other"
+    stacks = ["*"]
+    version = "23.3.2"
+`))
+		})
+
+		context("failure cases", func() {
+			context("when the buildpack.toml cannot be parsed", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(path, []byte("%%%"), 0644)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					err := setDeprecationDates(path, releases, 12)
+					Expect(err).To(MatchError(ContainSubstring("expected '.' or '=', but got '%' instead")))
+				})
+			})
+		})
+	})
+
+	context("deprecationDate", func() {
+		context("when a newer minor version has been released", func() {
+			it("returns the given number of months after its first release", func() {
+				date := deprecationDate(semver.MustParse("23.3.2"), releases, 12)
+				Expect(date).NotTo(BeNil())
+				Expect(*date).To(Equal(time.Date(2025, time.February, 3, 0, 0, 0, 0, time.UTC)))
+
+				date = deprecationDate(semver.MustParse("24.0.0"), releases, 6)
+				Expect(date).NotTo(BeNil())
+				Expect(*date).To(Equal(time.Date(2024, time.December, 20, 0, 0, 0, 0, time.UTC)))
+			})
+		})
+
+		context("when no newer minor version has been released", func() {
+			it("returns no date", func() {
+				Expect(deprecationDate(semver.MustParse("24.1.0"), releases, 12)).To(BeNil())
+			})
+		})
+
+		context("when the only newer minor version is a pre-release", func() {
+			it.Before(func() {
+				releases = append(releases, release("24.2.0-b.1", "2024-07-01T10:00:00Z"))
+			})
+
+			it("returns no date", func() {
+				Expect(deprecationDate(semver.MustParse("24.1.0"), releases, 12)).To(BeNil())
+			})
+		})
+
+		context("when the support period is not positive", func() {
+			it("returns no date", func() {
+				Expect(deprecationDate(semver.MustParse("23.3.2"), releases, 0)).To(BeNil())
+				Expect(deprecationDate(semver.MustParse("23.3.2"), releases, -1)).To(BeNil())
+			})
+		})
+	})
+}
@@ -0,0 +1,116 @@
+// Command requirespython sets the requires-python field of each pip
+// dependency in a buildpack.toml to the Requires-Python metadata of its
+// release on PyPI.
+//
+// The buildpack.toml is rewritten from the retrieved metadata by jam, which
+// only keeps the fields of cargo.ConfigMetadataDependency, so this command
+// runs after every update of the dependencies.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/Masterminds/semver/v3"
+	"github.com/paketo-buildpacks/libdependency/upstream"
+)
+
+type PyPiProductMetadataRaw struct {
+	Releases map[string][]struct {
+		PackageType    string `json:"packagetype"`
+		RequiresPython string `json:"requires_python"`
+	} `json:"releases"`
+}
+
+func main() {
+	var buildpackTomlPath string
+	flag.StringVar(&buildpackTomlPath, "buildpack-toml-path", "", "path to the buildpack.toml file to update")
+	flag.Parse()
+
+	if buildpackTomlPath == "" {
+		log.Fatal("missing required flag --buildpack-toml-path")
+	}
+
+	var pypiMetadata PyPiProductMetadataRaw
+	err := upstream.GetAndUnmarshal("https://pypi.org/pypi/pip/json", &pypiMetadata)
+	if err != nil {
+		log.Fatal(fmt.Errorf("could not retrieve releases from upstream: %w", err))
+	}
+
+	err = setRequiresPython(buildpackTomlPath, requiresPython(pypiMetadata))
+	if err != nil {
+		log.Fatal(fmt.Errorf("could not set requires-python in %s: %w", buildpackTomlPath, err))
+	}
+}
+
+// requiresPython maps the semver version of each release of pip to the
+// Requires-Python specifier of its sdist.
+func requiresPython(pypiMetadata PyPiProductMetadataRaw) map[string]string {
+	specifiers := map[string]string{}
+	for version, releasesForVersion := range pypiMetadata.Releases {
+		semverVersion, err := semver.NewVersion(version)
+		if err != nil {
+			continue
+		}
+
+		for _, release := range releasesForVersion {
+			if release.PackageType == "sdist" && strings.TrimSpace(release.RequiresPython) != "" {
+				specifiers[semverVersion.String()] = strings.TrimSpace(release.RequiresPython)
+			}
+		}
+	}
+
+	return specifiers
+}
+
+// setRequiresPython sets the requires-python field of each pip dependency in
+// the buildpack.toml at the given path to the given specifier for its
+// version. The file is encoded in the same way as jam writes it.
+func setRequiresPython(path string, specifiers map[string]string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	var config map[string]interface{}
+	_, err = toml.DecodeFile(path, &config)
+	if err != nil {
+		return err
+	}
+
+	metadata, _ := config["metadata"].(map[string]interface{})
+	dependencies, _ := metadata["dependencies"].([]map[string]interface{})
+	for _, dependency := range dependencies {
+		id, _ := dependency["id"].(string)
+		version, _ := dependency["version"].(string)
+		if id != "pip" {
+			continue
+		}
+
+		semverVersion, err := semver.NewVersion(version)
+		if err != nil {
+			return fmt.Errorf("could not parse version %q: %w", version, err)
+		}
+
+		specifier, ok := specifiers[semverVersion.String()]
+		if !ok {
+			delete(dependency, "requires-python")
+			continue
+		}
+
+		dependency["requires-python"] = specifier
+	}
+
+	buffer := bytes.NewBuffer(nil)
+	err = toml.NewEncoder(buffer).Encode(config)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, buffer.Bytes(), info.Mode().Perm())
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestRequiresPython(t *testing.T) {
+	spec.Run(t, "requirespython", testRequiresPython, spec.Report(report.Terminal{}))
+}
+
+func testRequiresPython(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		path string
+	)
+
+	it.Before(func() {
+		path = filepath.Join(t.TempDir(), "buildpack.toml")
+		Expect(os.WriteFile(path, []byte(`api = "0.7"
+
+[metadata]
+
+  [[metadata.dependencies]]
+    id = "pip"
+    requires-python = ">=3.6"
+    stacks = ["*"]
+    version = "23.3.0"
+
+  [[metadata.dependencies]]
+    id = "pip"
+    stacks = ["*"]
+    version = "24.0.0"
+
+  [[metadata.dependencies]]
+    id = "other"
+    stacks = ["*"]
+    version = "24.0.0"
+`), 0644)).To(Succeed())
+	})
+
+	context("requiresPython", func() {
+		it("maps the semver version of each release to the Requires-Python of its sdist", func() {
+			var metadata PyPiProductMetadataRaw
+			metadata.Releases = map[string][]struct {
+				PackageType    string `json:"packagetype"`
+				RequiresPython string `json:"requires_python"`
+			}{
+				"24.0": {
+					{PackageType: "bdist_wheel", RequiresPython: ">=3.8"},
+					{PackageType: "sdist", RequiresPython: " >=3.7 "},
+				},
+				"23.3":        {{PackageType: "sdist"}},
+				"not-semver!": {{PackageType: "sdist", RequiresPython: ">=3.7"}},
+			}
+
+			Expect(requiresPython(metadata)).To(Equal(map[string]string{"24.0.0": ">=3.7"}))
+		})
+	})
+
+	context("setRequiresPython", func() {
+		it("sets requires-python on the pip dependencies only", func() {
+			Expect(setRequiresPython(path, map[string]string{"24.0.0": ">=3.7"})).To(Succeed())
+
+			content, err := os.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(Equal(`api = "0.7"
+
+[metadata]
+
+  [[metadata.dependencies]]
+    id = "pip"
+    stacks = ["*"]
+    version = "23.3.0"
+
+  [[metadata.dependencies]]
+    id = "pip"
+    requires-python = ">=3.7"
+    stacks = ["*"]
+    version = "24.0.0"
+
+  [[metadata.dependencies]]
+    id = "other"
+    stacks = ["*"]
+    version = "24.0.0"
+`))
+		})
+
+		context("failure cases", func() {
+			context("when the buildpack.toml cannot be parsed", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(path, []byte("%%%"), 0644)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					err := setRequiresPython(path, nil)
+					Expect(err).To(MatchError(ContainSubstring("expected '.' or '=', but got '%' instead")))
+				})
+			})
+		})
+	})
+}
@@ -28,6 +28,19 @@ check_version() {
   fi
 }
 
+check_wheel() {
+  expected_version="$1"
+
+  if [[ "${expected_version}" =~ [0-9]+.[0-9]+.0 ]]; then
+    expected_version="$(echo "${expected_version}" | cut -d '.' -f1,2)"
+  fi
+
+  if [[ ! -f "pip/pip-${expected_version}-py3-none-any.whl" ]]; then
+    echo "Wheel pip-${expected_version}-py3-none-any.whl is missing from the tarball"
+    exit 1
+  fi
+}
+
 main() {
   local tarballPath expectedVersion
   tarballPath=""
@@ -70,6 +83,7 @@ main() {
 
   extract_tarball "${tarballPath}"
   check_version "${expectedVersion}"
+  check_wheel "${expectedVersion}"
 
   echo "All tests passed!"
 }
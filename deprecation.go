@@ -0,0 +1,64 @@
+package pip
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/paketo-buildpacks/packit/v2/postal"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+)
+
+// DefaultDeprecationWindowDays is the number of days before the deprecation
+// date of the selected pip dependency from which a warning is logged.
+const DefaultDeprecationWindowDays = 30
+
+// checkDeprecation logs a warning when the given dependency is deprecated, or
+// will be within $BP_PIP_DEPRECATION_WINDOW_DAYS days. With
+// $BP_PIP_FAIL_ON_DEPRECATED set, an error is returned instead once the
+// dependency is deprecated.
+func checkDeprecation(logger scribe.Emitter, dependency postal.Dependency, now time.Time) error {
+	windowDays := DefaultDeprecationWindowDays
+	if value, ok := os.LookupEnv("BP_PIP_DEPRECATION_WINDOW_DAYS"); ok {
+		var err error
+		windowDays, err = strconv.Atoi(value)
+		if err != nil || windowDays < 0 {
+			return fmt.Errorf("failed to parse BP_PIP_DEPRECATION_WINDOW_DAYS value %q: expected a non-negative number of days", value)
+		}
+	}
+
+	failOnDeprecated := false
+	if value, ok := os.LookupEnv("BP_PIP_FAIL_ON_DEPRECATED"); ok {
+		var err error
+		failOnDeprecated, err = strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("failed to parse BP_PIP_FAIL_ON_DEPRECATED value %q: %w", value, err)
+		}
+	}
+
+	deprecationDate := dependency.DeprecationDate
+	if deprecationDate.IsZero() {
+		return nil
+	}
+
+	date := deprecationDate.Format("2006-01-02")
+	switch {
+	case !deprecationDate.After(now):
+		if failOnDeprecated {
+			return fmt.Errorf("pip %s was deprecated on %s and BP_PIP_FAIL_ON_DEPRECATED is set: migrate your application to a supported version of pip", dependency.Version, date)
+		}
+
+		logger.Process("Warning: pip %s was deprecated on %s", dependency.Version, date)
+		logger.Subprocess("Migrate your application to a supported version of pip.")
+		logger.Break()
+
+	case deprecationDate.Before(now.AddDate(0, 0, windowDays)):
+		days := int(deprecationDate.Sub(now).Hours()/24) + 1
+		logger.Process("Warning: pip %s will be deprecated on %s (in %d day(s))", dependency.Version, date, days)
+		logger.Subprocess("Migrate your application to a supported version of pip before this time.")
+		logger.Break()
+	}
+
+	return nil
+}
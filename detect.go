@@ -1,12 +1,23 @@
 package pip
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
 	"regexp"
+	"strconv"
 
 	"github.com/paketo-buildpacks/packit/v2"
 )
 
+//go:generate faux --interface VersionParser --output fakes/version_parser.go
+
+// VersionParser defines the interface for determining the version of pip
+// requested by a file in the application source.
+type VersionParser interface {
+	ParseVersion(path string) (version string, err error)
+}
+
 // BuildPlanMetadata is the buildpack specific data included in build plan
 // requirements.
 type BuildPlanMetadata struct {
@@ -30,9 +41,15 @@ type BuildPlanMetadata struct {
 // and requires cpython OR python, python_packages, and requirements.
 //
 // If a version is provided via the $BP_PIP_VERSION environment variable, that
-// version of pip will be a requirement.
-func Detect() packit.DetectFunc {
-	return func(_ packit.DetectContext) (packit.DetectResult, error) {
+// version of pip will be a requirement. Likewise, a version of pip requested
+// in the .pip-version or pyproject.toml files of the application will be a
+// requirement. Versions may be given as PEP 440 version specifiers, or as the
+// keywords "latest" and "system" (see TranslateVersion).
+//
+// If $BP_PIP_LAUNCH is true, pip will be required at launch so that it is
+// available in the application image.
+func Detect(pyProjectParser, pipVersionParser VersionParser) packit.DetectFunc {
+	return func(context packit.DetectContext) (packit.DetectResult, error) {
 
 		requirements := []packit.BuildPlanRequirement{
 			{
@@ -46,25 +63,61 @@ func Detect() packit.DetectFunc {
 		pipVersion := os.Getenv("BP_PIP_VERSION")
 
 		if pipVersion != "" {
-			// Pip releases are of the form X.Y rather than X.Y.0, so in order
-			// to support selecting the exact version X.Y we have to up-convert
-			// X.Y to X.Y.0.
-			// Otherwise X.Y would match the latest patch release
-			// X.Y.Z if it is available.
-			var xDotYPattern = regexp.MustCompile(`^\d+\.\d+$`)
-			if xDotYPattern.MatchString(pipVersion) {
-				pipVersion = pipVersion + ".0"
-			}
-
 			requirements = append(requirements, packit.BuildPlanRequirement{
 				Name: Pip,
 				Metadata: BuildPlanMetadata{
 					VersionSource: "BP_PIP_VERSION",
-					Version:       pipVersion,
+					Version:       TranslateVersion(pipVersion),
+				},
+			})
+		}
+
+		fileVersion, err := pipVersionParser.ParseVersion(filepath.Join(context.WorkingDir, PipVersionFile))
+		if err != nil {
+			return packit.DetectResult{}, err
+		}
+
+		if fileVersion != "" {
+			requirements = append(requirements, packit.BuildPlanRequirement{
+				Name: Pip,
+				Metadata: BuildPlanMetadata{
+					VersionSource: PipVersionFile,
+					Version:       TranslateVersion(fileVersion),
+				},
+			})
+		}
+
+		pyProjectVersion, err := pyProjectParser.ParseVersion(filepath.Join(context.WorkingDir, PyProjectFile))
+		if err != nil {
+			return packit.DetectResult{}, err
+		}
+
+		if pyProjectVersion != "" {
+			requirements = append(requirements, packit.BuildPlanRequirement{
+				Name: Pip,
+				Metadata: BuildPlanMetadata{
+					VersionSource: PyProjectFile,
+					Version:       TranslateVersion(pyProjectVersion),
 				},
 			})
 		}
 
+		if value, ok := os.LookupEnv("BP_PIP_LAUNCH"); ok {
+			launch, err := strconv.ParseBool(value)
+			if err != nil {
+				return packit.DetectResult{}, fmt.Errorf("failed to parse BP_PIP_LAUNCH value %q: %w", value, err)
+			}
+
+			if launch {
+				requirements = append(requirements, packit.BuildPlanRequirement{
+					Name: Pip,
+					Metadata: BuildPlanMetadata{
+						Launch: true,
+					},
+				})
+			}
+		}
+
 		return packit.DetectResult{
 			Plan: packit.BuildPlan{
 				Provides: []packit.BuildPlanProvision{
@@ -75,3 +128,18 @@ func Detect() packit.DetectFunc {
 		}, nil
 	}
 }
+
+var xDotYPattern = regexp.MustCompile(`^\d+\.\d+$`)
+
+// normalizeVersion up-converts a version of the form X.Y to X.Y.0. Pip
+// releases are of the form X.Y rather than X.Y.0, so in order to support
+// selecting the exact version X.Y we have to up-convert X.Y to X.Y.0.
+// Otherwise X.Y would match the latest patch release X.Y.Z if it is
+// available.
+func normalizeVersion(version string) string {
+	if xDotYPattern.MatchString(version) {
+		return version + ".0"
+	}
+
+	return version
+}
@@ -1,10 +1,12 @@
 package pip_test
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/paketo-buildpacks/packit/v2"
 	"github.com/paketo-buildpacks/pip"
+	"github.com/paketo-buildpacks/pip/fakes"
 	"github.com/sclevine/spec"
 
 	. "github.com/onsi/gomega"
@@ -14,13 +16,21 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 	var (
 		Expect = NewWithT(t).Expect
 
+		pyProjectParser  *fakes.VersionParser
+		pipVersionParser *fakes.VersionParser
+
 		detect        packit.DetectFunc
 		detectContext packit.DetectContext
 	)
 
 	it.Before(func() {
-		detect = pip.Detect()
-		detectContext = packit.DetectContext{}
+		pyProjectParser = &fakes.VersionParser{}
+		pipVersionParser = &fakes.VersionParser{}
+
+		detect = pip.Detect(pyProjectParser, pipVersionParser)
+		detectContext = packit.DetectContext{
+			WorkingDir: "/working-dir",
+		}
 	})
 
 	context("detection", func() {
@@ -41,6 +51,9 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 					},
 				},
 			}))
+
+			Expect(pyProjectParser.ParseVersionCall.Receives.Path).To(Equal("/working-dir/pyproject.toml"))
+			Expect(pipVersionParser.ParseVersionCall.Receives.Path).To(Equal("/working-dir/.pip-version"))
 		})
 
 		context("when BP_PIP_VERSION is set", func() {
@@ -140,6 +153,63 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 				})
 			})
 
+			context("when the provided version is a PEP 440 specifier", func() {
+				it.Before(func() {
+					t.Setenv("BP_PIP_VERSION", "~=24.0")
+				})
+
+				it("selects the versions pip would select", func() {
+					result, err := detect(detectContext)
+
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result.Plan.Requires).To(ContainElement(packit.BuildPlanRequirement{
+						Name: pip.Pip,
+						Metadata: pip.BuildPlanMetadata{
+							Version:       ">=24.0.0, <25.0.0",
+							VersionSource: "BP_PIP_VERSION",
+						},
+					}))
+				})
+			})
+
+			context("when the provided version is latest", func() {
+				it.Before(func() {
+					t.Setenv("BP_PIP_VERSION", "latest")
+				})
+
+				it("selects any version", func() {
+					result, err := detect(detectContext)
+
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result.Plan.Requires).To(ContainElement(packit.BuildPlanRequirement{
+						Name: pip.Pip,
+						Metadata: pip.BuildPlanMetadata{
+							Version:       "*",
+							VersionSource: "BP_PIP_VERSION",
+						},
+					}))
+				})
+			})
+
+			context("when the provided version is system", func() {
+				it.Before(func() {
+					t.Setenv("BP_PIP_VERSION", "system")
+				})
+
+				it("requests the system pip", func() {
+					result, err := detect(detectContext)
+
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result.Plan.Requires).To(ContainElement(packit.BuildPlanRequirement{
+						Name: pip.Pip,
+						Metadata: pip.BuildPlanMetadata{
+							Version:       "system",
+							VersionSource: "BP_PIP_VERSION",
+						},
+					}))
+				})
+			})
+
 			context("when the provided version is of some other form", func() {
 				it.Before(func() {
 					t.Setenv("BP_PIP_VERSION", "some.other")
@@ -174,5 +244,214 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 			})
 		})
 
+		context("when the .pip-version file requests a version of pip", func() {
+			it.Before(func() {
+				pipVersionParser.ParseVersionCall.Returns.Version = "22.1.3"
+			})
+
+			it("returns a build plan that requires the version of pip from .pip-version", func() {
+				result, err := detect(detectContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Plan).To(Equal(packit.BuildPlan{
+					Provides: []packit.BuildPlanProvision{
+						{Name: pip.Pip},
+					},
+					Requires: []packit.BuildPlanRequirement{
+						{
+							Name: pip.CPython,
+							Metadata: pip.BuildPlanMetadata{
+								Build: true,
+							},
+						},
+						{
+							Name: pip.Pip,
+							Metadata: pip.BuildPlanMetadata{
+								Version:       "22.1.3",
+								VersionSource: ".pip-version",
+							},
+						},
+					},
+				}))
+			})
+
+			context("when the requested version is of the form X.Y", func() {
+				it.Before(func() {
+					pipVersionParser.ParseVersionCall.Returns.Version = "24.0"
+				})
+
+				it("selects the version X.Y.0", func() {
+					result, err := detect(detectContext)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(result.Plan.Requires).To(ContainElement(packit.BuildPlanRequirement{
+						Name: pip.Pip,
+						Metadata: pip.BuildPlanMetadata{
+							Version:       "24.0.0",
+							VersionSource: ".pip-version",
+						},
+					}))
+				})
+			})
+		})
+
+		context("when the pyproject.toml requests a version of pip", func() {
+			it.Before(func() {
+				pyProjectParser.ParseVersionCall.Returns.Version = ">=23, <25"
+			})
+
+			it("returns a build plan that requires the version of pip from pyproject.toml", func() {
+				result, err := detect(detectContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Plan).To(Equal(packit.BuildPlan{
+					Provides: []packit.BuildPlanProvision{
+						{Name: pip.Pip},
+					},
+					Requires: []packit.BuildPlanRequirement{
+						{
+							Name: pip.CPython,
+							Metadata: pip.BuildPlanMetadata{
+								Build: true,
+							},
+						},
+						{
+							Name: pip.Pip,
+							Metadata: pip.BuildPlanMetadata{
+								Version:       ">=23.0.0, <25.0.0",
+								VersionSource: "pyproject.toml",
+							},
+						},
+					},
+				}))
+			})
+
+			context("when the requested version is of the form X.Y", func() {
+				it.Before(func() {
+					pyProjectParser.ParseVersionCall.Returns.Version = "24.0"
+				})
+
+				it("selects the version X.Y.0", func() {
+					result, err := detect(detectContext)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(result.Plan.Requires).To(ContainElement(packit.BuildPlanRequirement{
+						Name: pip.Pip,
+						Metadata: pip.BuildPlanMetadata{
+							Version:       "24.0.0",
+							VersionSource: "pyproject.toml",
+						},
+					}))
+				})
+			})
+
+			context("when BP_PIP_VERSION is also set", func() {
+				it.Before(func() {
+					t.Setenv("BP_PIP_VERSION", "22.1.3")
+				})
+
+				it("returns a build plan that requires both versions", func() {
+					result, err := detect(detectContext)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(result.Plan.Requires).To(Equal([]packit.BuildPlanRequirement{
+						{
+							Name: pip.CPython,
+							Metadata: pip.BuildPlanMetadata{
+								Build: true,
+							},
+						},
+						{
+							Name: pip.Pip,
+							Metadata: pip.BuildPlanMetadata{
+								Version:       "22.1.3",
+								VersionSource: "BP_PIP_VERSION",
+							},
+						},
+						{
+							Name: pip.Pip,
+							Metadata: pip.BuildPlanMetadata{
+								Version:       ">=23.0.0, <25.0.0",
+								VersionSource: "pyproject.toml",
+							},
+						},
+					}))
+				})
+			})
+		})
+	})
+
+	context("when BP_PIP_LAUNCH is true", func() {
+		it.Before(func() {
+			t.Setenv("BP_PIP_LAUNCH", "true")
+		})
+
+		it("returns a build plan that requires pip at launch", func() {
+			result, err := detect(detectContext)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Plan.Requires).To(Equal([]packit.BuildPlanRequirement{
+				{
+					Name: pip.CPython,
+					Metadata: pip.BuildPlanMetadata{
+						Build: true,
+					},
+				},
+				{
+					Name: pip.Pip,
+					Metadata: pip.BuildPlanMetadata{
+						Launch: true,
+					},
+				},
+			}))
+		})
+	})
+
+	context("when BP_PIP_LAUNCH is false", func() {
+		it.Before(func() {
+			t.Setenv("BP_PIP_LAUNCH", "false")
+		})
+
+		it("does not require pip at launch", func() {
+			result, err := detect(detectContext)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Plan.Requires).To(HaveLen(1))
+		})
+	})
+
+	context("failure cases", func() {
+		context("when BP_PIP_LAUNCH cannot be parsed", func() {
+			it.Before(func() {
+				t.Setenv("BP_PIP_LAUNCH", "some-value")
+			})
+
+			it("returns an error", func() {
+				_, err := detect(detectContext)
+				Expect(err).To(MatchError(ContainSubstring(`failed to parse BP_PIP_LAUNCH value "some-value"`)))
+			})
+		})
+
+		context("when the .pip-version file cannot be parsed", func() {
+			it.Before(func() {
+				pipVersionParser.ParseVersionCall.Returns.Err = errors.New("failed to read .pip-version")
+			})
+
+			it("returns an error", func() {
+				_, err := detect(detectContext)
+				Expect(err).To(MatchError("failed to read .pip-version"))
+			})
+		})
+
+		context("when the pyproject.toml cannot be read", func() {
+			it.Before(func() {
+				pyProjectParser.ParseVersionCall.Returns.Err = errors.New("failed to read pyproject.toml")
+			})
+
+			it("returns an error", func() {
+				_, err := detect(detectContext)
+				Expect(err).To(MatchError("failed to read pyproject.toml"))
+			})
+		})
 	})
 }
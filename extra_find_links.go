@@ -0,0 +1,98 @@
+package pip
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/paketo-buildpacks/packit/v2/fs"
+)
+
+// extraFindLinks returns the distributions (wheels and sdists) in the
+// application directories listed in $BP_PIP_EXTRA_FIND_LINKS, separated by
+// colons or whitespace, along with a digest of their names and contents.
+func extraFindLinks(workingDir string) ([]string, string, error) {
+	dirs := strings.FieldsFunc(os.Getenv("BP_PIP_EXTRA_FIND_LINKS"), func(r rune) bool {
+		return r == ':' || unicode.IsSpace(r)
+	})
+
+	var files []string
+	for _, dir := range dirs {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(workingDir, dir)
+		}
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read BP_PIP_EXTRA_FIND_LINKS directory: %w", err)
+		}
+
+		for _, entry := range entries {
+			if entry.Type().IsRegular() && isDistribution(entry.Name()) {
+				files = append(files, filepath.Join(dir, entry.Name()))
+			}
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, "", nil
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		return filepath.Base(files[i]) < filepath.Base(files[j])
+	})
+
+	hash := sha256.New()
+	for _, file := range files {
+		fmt.Fprintf(hash, "%s\x00", filepath.Base(file))
+
+		content, err := os.Open(file)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read BP_PIP_EXTRA_FIND_LINKS distribution: %w", err)
+		}
+
+		_, err = io.Copy(hash, content)
+		content.Close()
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read BP_PIP_EXTRA_FIND_LINKS distribution: %w", err)
+		}
+	}
+
+	return files, fmt.Sprintf("sha256:%s", hex.EncodeToString(hash.Sum(nil))), nil
+}
+
+// deliverExtraFindLinks copies the given distributions into the destination
+// directory.
+func deliverExtraFindLinks(files []string, destination string) error {
+	err := os.MkdirAll(destination, os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("failed to deliver BP_PIP_EXTRA_FIND_LINKS: %w", err)
+	}
+
+	for _, file := range files {
+		err = fs.Copy(file, filepath.Join(destination, filepath.Base(file)))
+		if err != nil {
+			return fmt.Errorf("failed to deliver BP_PIP_EXTRA_FIND_LINKS: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// isDistribution reports whether the file with the given name is a wheel or
+// an sdist.
+func isDistribution(name string) bool {
+	for _, suffix := range []string{".whl", ".tar.gz", ".zip"} {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,6 +1,10 @@
 package fakes
 
-import "sync"
+import (
+	"sync"
+
+	"github.com/paketo-buildpacks/pip"
+)
 
 type InstallProcess struct {
 	ExecuteCall struct {
@@ -9,22 +13,24 @@ type InstallProcess struct {
 		Receives  struct {
 			SrcPath         string
 			TargetLayerPath string
+			Options         pip.InstallOptions
 		}
 		Returns struct {
 			Error error
 		}
-		Stub func(string, string) error
+		Stub func(string, string, pip.InstallOptions) error
 	}
 }
 
-func (f *InstallProcess) Execute(param1 string, param2 string) error {
+func (f *InstallProcess) Execute(param1 string, param2 string, param3 pip.InstallOptions) error {
 	f.ExecuteCall.mutex.Lock()
 	defer f.ExecuteCall.mutex.Unlock()
 	f.ExecuteCall.CallCount++
 	f.ExecuteCall.Receives.SrcPath = param1
 	f.ExecuteCall.Receives.TargetLayerPath = param2
+	f.ExecuteCall.Receives.Options = param3
 	if f.ExecuteCall.Stub != nil {
-		return f.ExecuteCall.Stub(param1, param2)
+		return f.ExecuteCall.Stub(param1, param2, param3)
 	}
 	return f.ExecuteCall.Returns.Error
 }
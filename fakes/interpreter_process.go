@@ -0,0 +1,29 @@
+package fakes
+
+import (
+	"sync"
+
+	"github.com/paketo-buildpacks/pip"
+)
+
+type InterpreterProcess struct {
+	ExecuteCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Returns   struct {
+			Interpreter pip.Interpreter
+			Error       error
+		}
+		Stub func() (pip.Interpreter, error)
+	}
+}
+
+func (f *InterpreterProcess) Execute() (pip.Interpreter, error) {
+	f.ExecuteCall.mutex.Lock()
+	defer f.ExecuteCall.mutex.Unlock()
+	f.ExecuteCall.CallCount++
+	if f.ExecuteCall.Stub != nil {
+		return f.ExecuteCall.Stub()
+	}
+	return f.ExecuteCall.Returns.Interpreter, f.ExecuteCall.Returns.Error
+}
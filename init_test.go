@@ -10,8 +10,14 @@ import (
 func TestUnitPip(t *testing.T) {
 	suite := spec.New("pip", spec.Report(report.Terminal{}))
 	suite("Detect", testDetect)
+	suite("GenerateFromDependencies", testGenerateFromDependencies)
 	suite("Build", testBuild)
 	suite("InstallProcess", testPipInstallProcess)
+	suite("PipVersionParser", testPipVersionParser)
+	suite("PipVersionProcess", testPipVersionProcess)
+	suite("PyProjectParser", testPyProjectParser)
+	suite("PythonInterpreterProcess", testPythonInterpreterProcess)
 	suite("SiteProcess", testSiteProcess)
+	suite("TranslateVersion", testTranslateVersion)
 	suite.Run(t)
 }
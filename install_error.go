@@ -0,0 +1,105 @@
+package pip
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// These errors describe the causes of pip installation failures that are
+// recognized from the output of pip.
+var (
+	ErrNoMatchingDistribution = errors.New("no matching distribution found")
+	ErrRequiresPython         = errors.New("pip does not support the python interpreter")
+	ErrDiskFull               = errors.New("no space left on device")
+	ErrReadOnlyFilesystem     = errors.New("read-only file system")
+	ErrMissingBuildBackend    = errors.New("build backend is not available")
+	ErrTLS                    = errors.New("TLS certificate verification failed")
+)
+
+// installFailures maps the output of a failed pip installation to its cause,
+// along with a hint to remediate it. The signatures are matched in order, so
+// that e.g. a Requires-Python mismatch is not reported as a missing
+// distribution.
+var installFailures = []struct {
+	pattern     *regexp.Regexp
+	cause       error
+	remediation string
+}{
+	{
+		pattern:     regexp.MustCompile(`(?i)no space left on device|\[Errno 28\]`),
+		cause:       ErrDiskFull,
+		remediation: "Free up disk space on the build host, or lower BP_PIP_CACHE_MAX_SIZE to reduce the size of the pip cache.",
+	},
+	{
+		pattern:     regexp.MustCompile(`(?i)read-only file system|\[Errno 30\]`),
+		cause:       ErrReadOnlyFilesystem,
+		remediation: "Make sure that the layers directory and $TMPDIR are writable by the build user.",
+	},
+	{
+		pattern:     regexp.MustCompile(`(?i)CERTIFICATE_VERIFY_FAILED|SSLError|SSLCertVerificationError`),
+		cause:       ErrTLS,
+		remediation: "Provide the CA certificates of your network with a binding of type ca-certificates.",
+	},
+	{
+		pattern:     regexp.MustCompile(`(?i)requires a different python|require a different python`),
+		cause:       ErrRequiresPython,
+		remediation: "Request a version of pip that supports the python interpreter with BP_PIP_VERSION, or request a newer version of python.",
+	},
+	{
+		pattern:     regexp.MustCompile(`(?i)BackendUnavailable|Cannot import '[^']+'|No module named '(setuptools|flit_core|hatchling|poetry)`),
+		cause:       ErrMissingBuildBackend,
+		remediation: "Install pip from a wheel, or provide the build backend of the pip sdist with BP_PIP_EXTRA_FIND_LINKS.",
+	},
+	{
+		pattern:     regexp.MustCompile(`(?i)no matching distribution found|could not find a version that satisfies the requirement`),
+		cause:       ErrNoMatchingDistribution,
+		remediation: "Pip is installed without an index: provide the missing distributions with BP_PIP_SOURCE or BP_PIP_EXTRA_FIND_LINKS.",
+	},
+}
+
+// An InstallError is returned by the PipInstallProcess when the cause of a
+// failed installation is recognized from the output of pip. It wraps both
+// the cause (e.g. ErrDiskFull) and the error of the command.
+type InstallError struct {
+	// Action describes the step of the installation that failed.
+	Action string
+
+	// Cause is one of the errors describing recognized failures.
+	Cause error
+
+	// Remediation is a hint to resolve the failure.
+	Remediation string
+
+	// Output is the output of the failed command.
+	Output string
+
+	// Err is the error of the failed command.
+	Err error
+}
+
+func (e InstallError) Error() string {
+	return fmt.Sprintf("failed to %s: %s:\n%s\nerror: %s", e.Action, e.Cause, e.Output, e.Err)
+}
+
+func (e InstallError) Unwrap() []error {
+	return []error{e.Cause, e.Err}
+}
+
+// installError returns an InstallError when the output of the failed
+// command is recognized, and the output wrapped with the error otherwise.
+func installError(action, output string, err error) error {
+	for _, failure := range installFailures {
+		if failure.pattern.MatchString(output) {
+			return InstallError{
+				Action:      action,
+				Cause:       failure.cause,
+				Remediation: failure.remediation,
+				Output:      output,
+				Err:         err,
+			}
+		}
+	}
+
+	return fmt.Errorf("failed to %s:\n%s\nerror: %w", action, output, err)
+}
@@ -112,5 +112,6 @@ func TestIntegration(t *testing.T) {
 	suite("Default", testDefault, spec.Parallel())
 	suite("LayerReuse", testLayerReuse, spec.Parallel())
 	suite("Offline", testOffline, spec.Parallel())
+	suite("Reproducible", testReproducible, spec.Parallel())
 	suite.Run(t)
 }
@@ -0,0 +1,95 @@
+package integration_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paketo-buildpacks/occam"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testReproducible(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		pack   occam.Pack
+		docker occam.Docker
+
+		imageIDs map[string]struct{}
+
+		firstName  string
+		secondName string
+		source     string
+	)
+
+	it.Before(func() {
+		var err error
+		firstName, err = occam.RandomName()
+		Expect(err).NotTo(HaveOccurred())
+
+		secondName, err = occam.RandomName()
+		Expect(err).NotTo(HaveOccurred())
+
+		pack = occam.NewPack()
+		docker = occam.NewDocker()
+
+		imageIDs = map[string]struct{}{}
+
+		source, err = occam.Source(filepath.Join("testdata", "default_app"))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	it.After(func() {
+		for id := range imageIDs {
+			Expect(docker.Image.Remove.Execute(id)).To(Succeed())
+		}
+
+		Expect(docker.Volume.Remove.Execute(occam.CacheVolumeNames(firstName))).To(Succeed())
+		Expect(docker.Volume.Remove.Execute(occam.CacheVolumeNames(secondName))).To(Succeed())
+
+		Expect(os.RemoveAll(source)).To(Succeed())
+	})
+
+	context("when the app is built twice without a cache", func() {
+		it("produces identical pip layers", func() {
+			var (
+				err  error
+				logs fmt.Stringer
+
+				firstImage  occam.Image
+				secondImage occam.Image
+			)
+
+			firstImage, logs, err = pack.WithNoColor().Build.
+				WithPullPolicy("never").
+				WithBuildpacks(
+					settings.Buildpacks.CPython.Online,
+					settings.Buildpacks.Pip.Online,
+					settings.Buildpacks.BuildPlan.Online,
+				).
+				Execute(firstName, source)
+			Expect(err).ToNot(HaveOccurred(), logs.String)
+
+			imageIDs[firstImage.ID] = struct{}{}
+
+			secondImage, logs, err = pack.WithNoColor().Build.
+				WithPullPolicy("never").
+				WithBuildpacks(
+					settings.Buildpacks.CPython.Online,
+					settings.Buildpacks.Pip.Online,
+					settings.Buildpacks.BuildPlan.Online,
+				).
+				Execute(secondName, source)
+			Expect(err).ToNot(HaveOccurred(), logs.String)
+
+			imageIDs[secondImage.ID] = struct{}{}
+
+			Expect(secondImage.Buildpacks[1].Key).To(Equal(buildpackInfo.Buildpack.ID))
+			Expect(secondImage.Buildpacks[1].Layers["pip"].SHA).To(Equal(firstImage.Buildpacks[1].Layers["pip"].SHA))
+		})
+	})
+}
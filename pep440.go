@@ -0,0 +1,147 @@
+package pip
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	clausePattern     = regexp.MustCompile(`^(===|==|~=|!=|<=|>=|<|>)\s*(.+)$`)
+	pep440Pattern     = regexp.MustCompile(`(?i)^v?(\d+(?:\.\d+)*)(?:[-_.]?(a|alpha|b|beta|rc|c|pre|preview)[-_.]?(\d*))?$`)
+	preReleaseLabels  = map[string]string{"a": "a", "alpha": "a", "b": "b", "beta": "b", "rc": "rc", "c": "rc", "pre": "rc", "preview": "rc"}
+	specifierOperator = regexp.MustCompile(`[<>!,]|==|~=`)
+)
+
+// TranslateVersion translates a requested version of pip, e.g. from
+// $BP_PIP_VERSION, into the semver constraint used to select a dependency.
+// PEP 440 version specifiers (e.g. ">=23,<25", "~=24.0" or "==24.0.*") are
+// translated so that they match the same versions as they would in pip. The
+// keyword "latest" selects the newest version of pip, and "system" requests
+// the pip bundled with the python interpreter. Other versions (e.g. "24.0",
+// "24.*" or "~24.0") are interpreted as semver constraints.
+func TranslateVersion(version string) string {
+	version = strings.TrimSpace(version)
+
+	switch strings.ToLower(version) {
+	case LatestVersion:
+		return "*"
+	case SystemVersion:
+		return SystemVersion
+	}
+
+	if specifierOperator.MatchString(version) {
+		return convertSpecifier(version)
+	}
+
+	return normalizeVersion(version)
+}
+
+// convertSpecifier converts a PEP 440 version specifier (e.g. ">=23,<25" or
+// "~=24.0") into the equivalent semver constraint. Versions are padded to
+// three release segments, since semver constraints treat missing segments as
+// wildcards (e.g. ">24.0" would not match 24.0.1), whereas pip pads them with
+// zeros. Clauses that cannot be translated are left unchanged.
+func convertSpecifier(specifier string) string {
+	var clauses []string
+	for _, clause := range strings.Split(specifier, ",") {
+		clause = strings.TrimSpace(clause)
+		if clause == "" {
+			continue
+		}
+
+		matches := clausePattern.FindStringSubmatch(clause)
+		if matches == nil {
+			clauses = append(clauses, clause)
+			continue
+		}
+
+		operator, version := matches[1], strings.TrimSpace(matches[2])
+		switch operator {
+		case "===":
+			// Arbitrary equality compares the versions as strings, which is
+			// closest to an exact semver version.
+			clause = "=" + semverVersion(version)
+
+		case "==", "!=":
+			if operator == "==" {
+				operator = "="
+			}
+
+			// A trailing wildcard matches any version with the given prefix, as
+			// in semver.
+			if !strings.HasSuffix(version, ".*") {
+				version = semverVersion(version)
+			}
+			clause = operator + version
+
+		case "~=":
+			// ~=X.Y matches any X.* release at or above X.Y, while ~=X.Y.Z
+			// matches any X.Y.* release at or above X.Y.Z.
+			clause = compatibleRelease(version)
+
+		default:
+			clause = operator + semverVersion(version)
+		}
+
+		clauses = append(clauses, clause)
+	}
+
+	return strings.Join(clauses, ", ")
+}
+
+// compatibleRelease translates the version of a compatible release clause
+// (~=) into a semver range.
+func compatibleRelease(version string) string {
+	matches := pep440Pattern.FindStringSubmatch(version)
+	if matches == nil {
+		return "~=" + version
+	}
+
+	segments := strings.Split(matches[1], ".")
+	if len(segments) < 2 || len(segments) > 3 {
+		return "~=" + version
+	}
+
+	upper := segments[:len(segments)-1]
+	last, err := strconv.Atoi(upper[len(upper)-1])
+	if err != nil {
+		return "~=" + version
+	}
+	upper[len(upper)-1] = strconv.Itoa(last + 1)
+
+	return fmt.Sprintf(">=%s, <%s", semverVersion(version), semverVersion(strings.Join(upper, ".")))
+}
+
+// semverVersion translates a PEP 440 version into a semver version with three
+// release segments, e.g. "24.0" into "24.0.0" and "24.1rc1" into
+// "24.1.0-rc.1". Versions that cannot be represented (e.g. with an epoch, a
+// post-release or more than three release segments) are left unchanged.
+func semverVersion(version string) string {
+	matches := pep440Pattern.FindStringSubmatch(version)
+	if matches == nil {
+		return version
+	}
+
+	segments := strings.Split(matches[1], ".")
+	if len(segments) > 3 {
+		return version
+	}
+
+	for len(segments) < 3 {
+		segments = append(segments, "0")
+	}
+
+	result := strings.Join(segments, ".")
+	if matches[2] != "" {
+		number := matches[3]
+		if number == "" {
+			number = "0"
+		}
+
+		result = fmt.Sprintf("%s-%s.%s", result, preReleaseLabels[strings.ToLower(matches[2])], number)
+	}
+
+	return result
+}
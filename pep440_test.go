@@ -0,0 +1,102 @@
+package pip_test
+
+import (
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+	pip "github.com/paketo-buildpacks/pip"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testTranslateVersion(t *testing.T, context spec.G, it spec.S) {
+	var Expect = NewWithT(t).Expect
+
+	// matches reports whether the translated version matches each of the given
+	// versions, as semver does when selecting a dependency.
+	matches := func(version string, versions ...string) map[string]bool {
+		constraint, err := semver.NewConstraint(pip.TranslateVersion(version))
+		Expect(err).NotTo(HaveOccurred(), version)
+
+		result := map[string]bool{}
+		for _, v := range versions {
+			result[v] = constraint.Check(semver.MustParse(v))
+		}
+
+		return result
+	}
+
+	context("TranslateVersion", func() {
+		context("when the version is a PEP 440 specifier", func() {
+			it("matches the same versions as pip", func() {
+				// The expected results are those of
+				// packaging.specifiers.SpecifierSet(specifier).contains(version),
+				// which pip uses to select versions.
+				for _, example := range []struct {
+					specifier string
+					pip       map[string]bool
+				}{
+					{">=23,<25", map[string]bool{"22.3.1": false, "23.0": true, "24.0": true, "24.3.1": true, "25.0": false}},
+					{"~=24.0", map[string]bool{"23.3.2": false, "24.0": true, "24.2": true, "24.3.1": true, "25.0": false}},
+					{"~=24.0.1", map[string]bool{"24.0": false, "24.0.1": true, "24.0.5": true, "24.1": false}},
+					{"==24.0.*", map[string]bool{"23.3.2": false, "24.0": true, "24.0.1": true, "24.1": false}},
+					{"==24.0", map[string]bool{"24.0": true, "24.0.1": false, "24.1": false}},
+					{"==24", map[string]bool{"24.0": true, "24.0.1": false}},
+					{"===24.0", map[string]bool{"24.0": true, "24.0.1": false}},
+					{">24.0", map[string]bool{"24.0": false, "24.0.1": true, "24.1": true}},
+					{">=24.0", map[string]bool{"23.3.2": false, "24.0": true, "24.0.1": true}},
+					{"<=24.0", map[string]bool{"23.3.2": true, "24.0": true, "24.0.1": false}},
+					{"<24", map[string]bool{"23.3.2": true, "24.0": false}},
+					{"!=24.0", map[string]bool{"24.0": false, "24.0.1": true}},
+					{"!=24.0.*", map[string]bool{"24.0": false, "24.0.1": false, "24.1": true}},
+					{">=23.0, !=23.1.*, <24", map[string]bool{"23.0": true, "23.1": false, "23.1.2": false, "23.3.2": true, "24.0": false}},
+					{" >= 23.0 , < 24 ", map[string]bool{"22.3.1": false, "23.3.2": true, "24.0": false}},
+				} {
+					versions := make([]string, 0, len(example.pip))
+					for version := range example.pip {
+						versions = append(versions, version)
+					}
+
+					Expect(matches(example.specifier, versions...)).To(Equal(example.pip), example.specifier)
+				}
+			})
+
+			it("translates pre-release versions", func() {
+				Expect(pip.TranslateVersion("==24.1rc1")).To(Equal("=24.1.0-rc.1"))
+				Expect(pip.TranslateVersion(">=24.1b2")).To(Equal(">=24.1.0-b.2"))
+			})
+
+			it("leaves versions that cannot be represented in semver unchanged", func() {
+				Expect(pip.TranslateVersion("==1!24.0")).To(Equal("=1!24.0"))
+				Expect(pip.TranslateVersion("~=24")).To(Equal("~=24"))
+			})
+		})
+
+		context("when the version is a keyword", func() {
+			it("translates latest into any version", func() {
+				Expect(pip.TranslateVersion("latest")).To(Equal("*"))
+				Expect(pip.TranslateVersion("Latest")).To(Equal("*"))
+			})
+
+			it("keeps system", func() {
+				Expect(pip.TranslateVersion("system")).To(Equal("system"))
+				Expect(pip.TranslateVersion(" SYSTEM ")).To(Equal("system"))
+			})
+		})
+
+		context("when the version is not a PEP 440 specifier", func() {
+			it("selects the exact version for X.Y", func() {
+				Expect(pip.TranslateVersion("24.0")).To(Equal("24.0.0"))
+				Expect(matches("24.0", "24.0", "24.0.1")).To(Equal(map[string]bool{"24.0": true, "24.0.1": false}))
+			})
+
+			it("keeps semver constraints", func() {
+				Expect(pip.TranslateVersion("24.0.1")).To(Equal("24.0.1"))
+				Expect(pip.TranslateVersion("24.*")).To(Equal("24.*"))
+				Expect(pip.TranslateVersion("~24.0")).To(Equal("~24.0"))
+				Expect(pip.TranslateVersion("^24.0")).To(Equal("^24.0"))
+			})
+		})
+	})
+}
@@ -3,8 +3,14 @@ package pip
 import (
 	"bytes"
 	"fmt"
-	"github.com/paketo-buildpacks/packit/v2/pexec"
+	"io"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2/pexec"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
 )
 
 //go:generate faux --interface Executable --output fakes/executable.go
@@ -17,30 +23,166 @@ type Executable interface {
 // PipInstallProcess implements the InstallProcess interface.
 type PipInstallProcess struct {
 	executable Executable
+	logger     scribe.Emitter
 }
 
 // NewPipInstallProcess creates an instance of the PipInstallProcess given an Executable that runs `python`.
-func NewPipInstallProcess(executable Executable) PipInstallProcess {
+// At the debug log level, the output of the Executable is streamed to the logger.
+func NewPipInstallProcess(executable Executable, logger scribe.Emitter) PipInstallProcess {
 	return PipInstallProcess{
 		executable: executable,
+		logger:     logger,
 	}
 }
 
 // Execute installs the pip binary from source code located in the given srcPath into the a layer path designated by targetLayerPath.
-func (p PipInstallProcess) Execute(srcPath, targetLayerPath string) error {
+// When the srcPath contains a prebuilt pip wheel, the wheel is installed instead of building pip from source.
+// In venv mode, a virtual environment is created in the targetLayerPath and pip is installed into it.
+// When the cause of a failure is recognized from the output of pip, an InstallError is returned.
+// The layer is made reproducible by honoring $SOURCE_DATE_EPOCH for its bytecode and file times.
+func (p PipInstallProcess) Execute(srcPath, targetLayerPath string, options InstallOptions) error {
 	buffer := bytes.NewBuffer(nil)
+	output := io.MultiWriter(buffer, p.logger.Debug.ActionWriter)
 
-	err := p.executable.Execute(pexec.Execution{
-		// Install pip from source with the pip that comes pre-installed with cpython
-		Args: []string{"-m", "pip", "install", srcPath, "--user", "--no-index", fmt.Sprintf("--find-links=%s", srcPath)},
-		// Set the PYTHONUSERBASE to ensure that pip is installed to the newly created target layer.
-		Env:    append(os.Environ(), fmt.Sprintf("PYTHONUSERBASE=%s", targetLayerPath)),
-		Stdout: buffer,
-		Stderr: buffer,
-	})
+	wheels, err := filepath.Glob(filepath.Join(srcPath, PipWheelPattern))
 	if err != nil {
-		return fmt.Errorf("failed to configure pip:\n%s\nerror: %w", buffer.String(), err)
+		return fmt.Errorf("failed to look up pip wheel: %w", err)
+	}
+
+	pkg := srcPath
+	if len(wheels) > 0 {
+		sort.Strings(wheels)
+		pkg = wheels[len(wheels)-1]
+	} else if !isSourceTree(srcPath) {
+		// The srcPath may only contain a pip sdist, e.g. when it is provided by
+		// the application.
+		var sdists []string
+		for _, pattern := range []string{PipSdistPattern, PipZipSdistPattern} {
+			matches, err := filepath.Glob(filepath.Join(srcPath, pattern))
+			if err != nil {
+				return fmt.Errorf("failed to look up pip sdist: %w", err)
+			}
+			sdists = append(sdists, matches...)
+		}
+
+		if len(sdists) > 0 {
+			sort.Strings(sdists)
+			pkg = sdists[len(sdists)-1]
+		}
 	}
 
-	return nil
+	// With $SOURCE_DATE_EPOCH set, python compiles bytecode with hash-based
+	// invalidation rather than embedding the modification times of the
+	// sources, so that the layer is reproducible.
+	epoch, err := sourceDateEpoch()
+	if err != nil {
+		return err
+	}
+	reproducible := []string{fmt.Sprintf("SOURCE_DATE_EPOCH=%d", epoch), "PYTHONHASHSEED=0"}
+
+	// Install pip with the pip that comes pre-installed with cpython, setting
+	// the PYTHONUSERBASE to ensure that pip is installed to the newly created
+	// target layer.
+	args := []string{"-m", "pip", "install", pkg, "--user", "--no-index", "--compile", fmt.Sprintf("--find-links=%s", srcPath)}
+	env := append(append(os.Environ(), fmt.Sprintf("PYTHONUSERBASE=%s", targetLayerPath)), reproducible...)
+
+	if options.Venv {
+		execution := pexec.Execution{
+			Args:   []string{"-m", "venv", targetLayerPath},
+			Env:    append(os.Environ(), reproducible...),
+			Stdout: output,
+			Stderr: output,
+		}
+		logExecution(p.logger, execution)
+
+		err = p.executable.Execute(execution)
+		if err != nil {
+			return installError("create virtual environment", buffer.String(), err)
+		}
+
+		// Replace the pip that the virtual environment was created with using
+		// the pip of the virtual environment itself.
+		args = []string{"-m", "pip", "install", pkg, "--force-reinstall", "--no-index", "--compile", fmt.Sprintf("--find-links=%s", srcPath)}
+		env = append(venvEnvironment(targetLayerPath), reproducible...)
+	}
+
+	for _, findLinks := range options.FindLinks {
+		args = append(args, fmt.Sprintf("--find-links=%s", findLinks))
+	}
+
+	if options.BreakSystemPackages {
+		env = append(env, "PIP_BREAK_SYSTEM_PACKAGES=1")
+	}
+
+	execution := pexec.Execution{
+		Args:   args,
+		Env:    env,
+		Stdout: output,
+		Stderr: output,
+	}
+	logExecution(p.logger, execution)
+
+	err = p.executable.Execute(execution)
+	if err != nil {
+		return installError("configure pip", buffer.String(), err)
+	}
+
+	return normalizeModTimes(targetLayerPath, epoch)
+}
+
+// isSourceTree reports whether the given path contains the source code of a
+// python project.
+func isSourceTree(path string) bool {
+	for _, name := range []string{PyProjectFile, "setup.py"} {
+		if _, err := os.Stat(filepath.Join(path, name)); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// venvEnvironment returns the environment for running the python of the
+// virtual environment at the given path, which is looked up on the $PATH.
+func venvEnvironment(venvPath string) []string {
+	var env []string
+	for _, variable := range os.Environ() {
+		if strings.HasPrefix(variable, "PATH=") || strings.HasPrefix(variable, "VIRTUAL_ENV=") {
+			continue
+		}
+		env = append(env, variable)
+	}
+
+	path := filepath.Join(venvPath, "bin")
+	if existing, ok := os.LookupEnv("PATH"); ok && existing != "" {
+		path = path + string(os.PathListSeparator) + existing
+	}
+
+	return append(env, fmt.Sprintf("PATH=%s", path), fmt.Sprintf("VIRTUAL_ENV=%s", venvPath))
+}
+
+// logExecution logs the command line of the given python execution at the
+// debug log level, along with the variables that its environment adds to or
+// changes in the build environment.
+func logExecution(logger scribe.Emitter, execution pexec.Execution) {
+	logger.Debug.Subprocess("Running 'python %s'", strings.Join(execution.Args, " "))
+
+	current := map[string]bool{}
+	for _, variable := range os.Environ() {
+		current[variable] = true
+	}
+
+	var delta []string
+	for _, variable := range execution.Env {
+		if !current[variable] {
+			delta = append(delta, variable)
+		}
+	}
+
+	if len(delta) > 0 {
+		logger.Debug.Action("With environment:")
+		for _, variable := range delta {
+			logger.Debug.Detail("%s", variable)
+		}
+	}
 }
@@ -1,17 +1,21 @@
 package pip_test
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/paketo-buildpacks/packit/v2/pexec"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
 	pip "github.com/paketo-buildpacks/pip"
 	"github.com/paketo-buildpacks/pip/fakes"
 	"github.com/sclevine/spec"
 
 	. "github.com/onsi/gomega"
+	. "github.com/paketo-buildpacks/packit/v2/matchers"
 )
 
 func testPipInstallProcess(t *testing.T, context spec.G, it spec.S) {
@@ -21,6 +25,8 @@ func testPipInstallProcess(t *testing.T, context spec.G, it spec.S) {
 		srcLayerPath    string
 		targetLayerPath string
 		executable      *fakes.Executable
+		buffer          *bytes.Buffer
+		executions      []pexec.Execution
 
 		pipInstallProcess pip.PipInstallProcess
 	)
@@ -33,19 +39,236 @@ func testPipInstallProcess(t *testing.T, context spec.G, it spec.S) {
 		targetLayerPath, err = os.MkdirTemp("", "pip")
 		Expect(err).NotTo(HaveOccurred())
 
+		executions = nil
+		buffer = bytes.NewBuffer(nil)
 		executable = &fakes.Executable{}
+		executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+			executions = append(executions, execution)
+			return nil
+		}
 
-		pipInstallProcess = pip.NewPipInstallProcess(executable)
+		pipInstallProcess = pip.NewPipInstallProcess(executable, scribe.NewEmitter(buffer))
 	})
 
 	context("Execute", func() {
 		context("there is a pip dependency to install", func() {
 			it("installs it to the pip layer", func() {
-				err := pipInstallProcess.Execute(srcLayerPath, targetLayerPath)
+				err := pipInstallProcess.Execute(srcLayerPath, targetLayerPath, pip.InstallOptions{})
 				Expect(err).NotTo(HaveOccurred())
 
-				Expect(executable.ExecuteCall.Receives.Execution.Env).To(Equal(append(os.Environ(), fmt.Sprintf("PYTHONUSERBASE=%s", targetLayerPath))))
-				Expect(executable.ExecuteCall.Receives.Execution.Args).To(Equal([]string{"-m", "pip", "install", srcLayerPath, "--user", "--no-index", fmt.Sprintf("--find-links=%s", srcLayerPath)}))
+				Expect(executable.ExecuteCall.Receives.Execution.Env).To(Equal(append(os.Environ(),
+					fmt.Sprintf("PYTHONUSERBASE=%s", targetLayerPath),
+					"SOURCE_DATE_EPOCH=315532801",
+					"PYTHONHASHSEED=0",
+				)))
+				Expect(executable.ExecuteCall.Receives.Execution.Args).To(Equal([]string{"-m", "pip", "install", srcLayerPath, "--user", "--no-index", "--compile", fmt.Sprintf("--find-links=%s", srcLayerPath)}))
+			})
+		})
+
+		context("additional find links are given", func() {
+			it("installs from them as well", func() {
+				err := pipInstallProcess.Execute(srcLayerPath, targetLayerPath, pip.InstallOptions{FindLinks: []string{"some-find-links"}})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(executable.ExecuteCall.Receives.Execution.Args).To(Equal([]string{
+					"-m", "pip", "install", srcLayerPath, "--user", "--no-index", "--compile",
+					fmt.Sprintf("--find-links=%s", srcLayerPath), "--find-links=some-find-links",
+				}))
+			})
+		})
+
+		context("the python interpreter is externally managed", func() {
+			it("allows pip to break system packages", func() {
+				err := pipInstallProcess.Execute(srcLayerPath, targetLayerPath, pip.InstallOptions{BreakSystemPackages: true})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(executable.ExecuteCall.Receives.Execution.Env).To(Equal(append(os.Environ(),
+					fmt.Sprintf("PYTHONUSERBASE=%s", targetLayerPath),
+					"SOURCE_DATE_EPOCH=315532801",
+					"PYTHONHASHSEED=0",
+					"PIP_BREAK_SYSTEM_PACKAGES=1",
+				)))
+			})
+		})
+
+		context("the install mode is venv", func() {
+			it("creates a virtual environment in the pip layer and installs pip into it", func() {
+				err := pipInstallProcess.Execute(srcLayerPath, targetLayerPath, pip.InstallOptions{Venv: true})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(executions).To(HaveLen(2))
+				Expect(executions[0].Args).To(Equal([]string{"-m", "venv", targetLayerPath}))
+				Expect(executions[0].Env).To(Equal(append(os.Environ(), "SOURCE_DATE_EPOCH=315532801", "PYTHONHASHSEED=0")))
+
+				Expect(executions[1].Args).To(Equal([]string{"-m", "pip", "install", srcLayerPath, "--force-reinstall", "--no-index", "--compile", fmt.Sprintf("--find-links=%s", srcLayerPath)}))
+				Expect(executions[1].Env).To(ContainElements(
+					fmt.Sprintf("PATH=%s%c%s", filepath.Join(targetLayerPath, "bin"), os.PathListSeparator, os.Getenv("PATH")),
+					fmt.Sprintf("VIRTUAL_ENV=%s", targetLayerPath),
+					"SOURCE_DATE_EPOCH=315532801",
+				))
+				Expect(executions[1].Env).NotTo(ContainElement(fmt.Sprintf("PYTHONUSERBASE=%s", targetLayerPath)))
+			})
+
+			context("when the virtual environment cannot be created", func() {
+				it.Before(func() {
+					executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+						_, err := fmt.Fprintln(execution.Stderr, "no ensurepip")
+						Expect(err).NotTo(HaveOccurred())
+						return errors.New("venv failed")
+					}
+				})
+
+				it("returns an error", func() {
+					err := pipInstallProcess.Execute(srcLayerPath, targetLayerPath, pip.InstallOptions{Venv: true})
+					Expect(err).To(MatchError(ContainSubstring("failed to create virtual environment")))
+					Expect(err).To(MatchError(ContainSubstring("no ensurepip")))
+					Expect(err).To(MatchError(ContainSubstring("venv failed")))
+				})
+			})
+		})
+
+		context("the pip dependency contains a prebuilt wheel", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(srcLayerPath, "pip-21.0-py3-none-any.whl"), nil, 0600)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(srcLayerPath, "pip-21.0.tar.gz"), nil, 0600)).To(Succeed())
+			})
+
+			it("installs the wheel to the pip layer", func() {
+				err := pipInstallProcess.Execute(srcLayerPath, targetLayerPath, pip.InstallOptions{})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(executable.ExecuteCall.Receives.Execution.Args).To(Equal([]string{
+					"-m", "pip", "install", filepath.Join(srcLayerPath, "pip-21.0-py3-none-any.whl"),
+					"--user", "--no-index", "--compile", fmt.Sprintf("--find-links=%s", srcLayerPath),
+				}))
+			})
+		})
+
+		context("the pip dependency only contains an sdist", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(srcLayerPath, "pip-24.0.tar.gz"), nil, 0600)).To(Succeed())
+			})
+
+			it("installs the sdist to the pip layer", func() {
+				err := pipInstallProcess.Execute(srcLayerPath, targetLayerPath, pip.InstallOptions{})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(executable.ExecuteCall.Receives.Execution.Args).To(Equal([]string{
+					"-m", "pip", "install", filepath.Join(srcLayerPath, "pip-24.0.tar.gz"),
+					"--user", "--no-index", "--compile", fmt.Sprintf("--find-links=%s", srcLayerPath),
+				}))
+			})
+
+			context("when the sdist is a zip archive", func() {
+				it.Before(func() {
+					Expect(os.Remove(filepath.Join(srcLayerPath, "pip-24.0.tar.gz"))).To(Succeed())
+					Expect(os.WriteFile(filepath.Join(srcLayerPath, "pip-24.0.zip"), nil, 0600)).To(Succeed())
+				})
+
+				it("installs the sdist to the pip layer", func() {
+					err := pipInstallProcess.Execute(srcLayerPath, targetLayerPath, pip.InstallOptions{})
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(executable.ExecuteCall.Receives.Execution.Args[3]).To(Equal(filepath.Join(srcLayerPath, "pip-24.0.zip")))
+				})
+			})
+
+			context("when the pip source code is also present", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(filepath.Join(srcLayerPath, "pyproject.toml"), nil, 0600)).To(Succeed())
+				})
+
+				it("installs the source code", func() {
+					err := pipInstallProcess.Execute(srcLayerPath, targetLayerPath, pip.InstallOptions{})
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(executable.ExecuteCall.Receives.Execution.Args[3]).To(Equal(srcLayerPath))
+				})
+			})
+		})
+
+		context("SOURCE_DATE_EPOCH is set", func() {
+			it.Before(func() {
+				t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+
+				executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+					Expect(os.MkdirAll(filepath.Join(targetLayerPath, "lib", "site-packages", "pip"), os.ModePerm)).To(Succeed())
+					Expect(os.WriteFile(filepath.Join(targetLayerPath, "lib", "site-packages", "pip", "__init__.py"), nil, 0600)).To(Succeed())
+					return nil
+				}
+			})
+
+			it("compiles the bytecode and normalizes the modification times of the layer with it", func() {
+				err := pipInstallProcess.Execute(srcLayerPath, targetLayerPath, pip.InstallOptions{})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(executable.ExecuteCall.Receives.Execution.Env).To(ContainElement("SOURCE_DATE_EPOCH=1700000000"))
+
+				for _, path := range []string{
+					targetLayerPath,
+					filepath.Join(targetLayerPath, "lib", "site-packages"),
+					filepath.Join(targetLayerPath, "lib", "site-packages", "pip", "__init__.py"),
+				} {
+					info, err := os.Stat(path)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(info.ModTime().Unix()).To(Equal(int64(1700000000)), path)
+				}
+			})
+
+			context("when SOURCE_DATE_EPOCH is not a number", func() {
+				it.Before(func() {
+					t.Setenv("SOURCE_DATE_EPOCH", "yesterday")
+				})
+
+				it("returns an error", func() {
+					err := pipInstallProcess.Execute(srcLayerPath, targetLayerPath, pip.InstallOptions{})
+					Expect(err).To(MatchError(ContainSubstring(`failed to parse SOURCE_DATE_EPOCH value "yesterday"`)))
+				})
+			})
+		})
+
+		context("the log level is debug", func() {
+			it.Before(func() {
+				executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+					_, err := fmt.Fprintln(execution.Stdout, "Processing ./pip-24.0-py3-none-any.whl")
+					Expect(err).NotTo(HaveOccurred())
+					_, err = fmt.Fprintln(execution.Stdout, "Successfully installed pip-24.0")
+					Expect(err).NotTo(HaveOccurred())
+					return nil
+				}
+
+				pipInstallProcess = pip.NewPipInstallProcess(executable, scribe.NewEmitter(buffer).WithLevel("DEBUG"))
+			})
+
+			it("streams the output of pip along with the command line and environment changes", func() {
+				err := pipInstallProcess.Execute(srcLayerPath, targetLayerPath, pip.InstallOptions{BreakSystemPackages: true})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).To(ContainLines(
+					fmt.Sprintf("    Running 'python -m pip install %s --user --no-index --compile --find-links=%s'", srcLayerPath, srcLayerPath),
+					"      With environment:",
+					fmt.Sprintf("        PYTHONUSERBASE=%s", targetLayerPath),
+					"        SOURCE_DATE_EPOCH=315532801",
+					"        PYTHONHASHSEED=0",
+					"        PIP_BREAK_SYSTEM_PACKAGES=1",
+					"      Processing ./pip-24.0-py3-none-any.whl",
+					"      Successfully installed pip-24.0",
+				))
+			})
+		})
+
+		context("the log level is not debug", func() {
+			it("does not stream the output of pip", func() {
+				executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+					_, err := fmt.Fprintln(execution.Stdout, "Successfully installed pip-24.0")
+					Expect(err).NotTo(HaveOccurred())
+					return nil
+				}
+
+				err := pipInstallProcess.Execute(srcLayerPath, targetLayerPath, pip.InstallOptions{})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).To(BeEmpty())
 			})
 		})
 
@@ -62,10 +285,91 @@ func testPipInstallProcess(t *testing.T, context spec.G, it spec.S) {
 				})
 
 				it("returns an error", func() {
-					err := pipInstallProcess.Execute(srcLayerPath, targetLayerPath)
+					err := pipInstallProcess.Execute(srcLayerPath, targetLayerPath, pip.InstallOptions{})
 					Expect(err).To(MatchError(ContainSubstring("installing pip failed")))
 					Expect(err).To(MatchError(ContainSubstring("stdout output")))
 					Expect(err).To(MatchError(ContainSubstring("stderr output")))
+
+					var installErr pip.InstallError
+					Expect(errors.As(err, &installErr)).To(BeFalse())
+				})
+			})
+
+			for _, failure := range []struct {
+				name   string
+				output string
+				cause  error
+			}{
+				{
+					name:   "no matching distribution",
+					output: "ERROR: Could not find a version that satisfies the requirement flit_core<4,>=3.2\nERROR: No matching distribution found for flit_core<4,>=3.2",
+					cause:  pip.ErrNoMatchingDistribution,
+				},
+				{
+					name:   "Requires-Python mismatch",
+					output: "ERROR: Package 'pip' requires a different Python: 3.7.17 not in '>=3.8'",
+					cause:  pip.ErrRequiresPython,
+				},
+				{
+					name:   "disk full",
+					output: "ERROR: Could not install packages due to an OSError: [Errno 28] No space left on device",
+					cause:  pip.ErrDiskFull,
+				},
+				{
+					name:   "read-only filesystem",
+					output: "ERROR: Could not install packages due to an OSError: [Errno 30] Read-only file system: '/layers/pip'",
+					cause:  pip.ErrReadOnlyFilesystem,
+				},
+				{
+					name:   "missing build backend",
+					output: "pip._vendor.pyproject_hooks._impl.BackendUnavailable: Cannot import 'setuptools.build_meta'",
+					cause:  pip.ErrMissingBuildBackend,
+				},
+				{
+					name:   "TLS",
+					output: "SSLError(SSLCertVerificationError(1, '[SSL: CERTIFICATE_VERIFY_FAILED] certificate verify failed: self-signed certificate in certificate chain'))",
+					cause:  pip.ErrTLS,
+				},
+			} {
+				failure := failure
+
+				context(fmt.Sprintf("the pip install process fails with a %s error", failure.name), func() {
+					it.Before(func() {
+						executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+							_, err := fmt.Fprintln(execution.Stderr, failure.output)
+							Expect(err).NotTo(HaveOccurred())
+							return errors.New("installing pip failed")
+						}
+					})
+
+					it("returns an install error describing the cause", func() {
+						err := pipInstallProcess.Execute(srcLayerPath, targetLayerPath, pip.InstallOptions{})
+						Expect(err).To(MatchError(ContainSubstring("failed to configure pip")))
+						Expect(err).To(MatchError(ContainSubstring(failure.output)))
+						Expect(errors.Is(err, failure.cause)).To(BeTrue())
+
+						var installErr pip.InstallError
+						Expect(errors.As(err, &installErr)).To(BeTrue())
+						Expect(installErr.Action).To(Equal("configure pip"))
+						Expect(installErr.Remediation).NotTo(BeEmpty())
+						Expect(installErr.Err).To(MatchError("installing pip failed"))
+					})
+				})
+			}
+
+			context("the virtual environment cannot be created because the disk is full", func() {
+				it.Before(func() {
+					executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+						_, err := fmt.Fprintln(execution.Stderr, "Error: [Errno 28] No space left on device")
+						Expect(err).NotTo(HaveOccurred())
+						return errors.New("creating venv failed")
+					}
+				})
+
+				it("returns an install error describing the cause", func() {
+					err := pipInstallProcess.Execute(srcLayerPath, targetLayerPath, pip.InstallOptions{Venv: true})
+					Expect(err).To(MatchError(ContainSubstring("failed to create virtual environment")))
+					Expect(errors.Is(err, pip.ErrDiskFull)).To(BeTrue())
 				})
 			})
 		})
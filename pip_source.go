@@ -0,0 +1,69 @@
+package pip
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2/fs"
+	"github.com/paketo-buildpacks/packit/v2/postal"
+)
+
+// sourceDependency describes the pip wheel or sdist at the given path,
+// provided by the application through $BP_PIP_SOURCE, as a dependency. The
+// checksum of the dependency is the SHA256 digest of the distribution, which
+// must match expectedSHA256 when it is set.
+func sourceDependency(path, expectedSHA256 string) (postal.Dependency, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return postal.Dependency{}, fmt.Errorf("failed to open BP_PIP_SOURCE: %w", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return postal.Dependency{}, fmt.Errorf("failed to read BP_PIP_SOURCE: %w", err)
+	}
+	digest := hex.EncodeToString(hash.Sum(nil))
+
+	expectedSHA256 = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(expectedSHA256), "sha256:"))
+	if expectedSHA256 != "" && expectedSHA256 != digest {
+		return postal.Dependency{}, fmt.Errorf("BP_PIP_SOURCE %s has SHA256 %s, which does not match BP_PIP_SOURCE_SHA256 %s", path, digest, expectedSHA256)
+	}
+
+	metadata, err := distributionMetadata(path)
+	if err != nil {
+		return postal.Dependency{}, fmt.Errorf("failed to read metadata of BP_PIP_SOURCE %s: %w", path, err)
+	}
+
+	if metadata == nil || distributionName(metadata) != Pip || metadata.Get("Version") == "" {
+		return postal.Dependency{}, fmt.Errorf("BP_PIP_SOURCE %s is not a pip wheel or sdist", path)
+	}
+
+	dependency := pipDependency(metadata.Get("Version"))
+	dependency.Checksum = fmt.Sprintf("sha256:%s", digest)
+	dependency.Source = path
+	dependency.Licenses = nil
+	if license := distributionLicense(metadata); license != "" {
+		dependency.Licenses = []string{license}
+	}
+
+	return dependency, nil
+}
+
+// deliverSource copies the pip distribution at the given path into the
+// destination. Other distributions next to it are left out: the build
+// requirements of a pip sdist are provided with $BP_PIP_EXTRA_FIND_LINKS.
+func deliverSource(path, destination string) error {
+	err := fs.Copy(path, filepath.Join(destination, filepath.Base(path)))
+	if err != nil {
+		return fmt.Errorf("failed to deliver BP_PIP_SOURCE: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package pip
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// PipVersionParser implements the VersionParser interface for the
+// .pip-version file of an application.
+type PipVersionParser struct{}
+
+// NewPipVersionParser creates an instance of the PipVersionParser.
+func NewPipVersionParser() PipVersionParser {
+	return PipVersionParser{}
+}
+
+// ParseVersion returns the version of pip requested by the .pip-version file
+// located at the given path. The version is the first line of the file that
+// is neither blank nor a comment, and may be written in the runtime.txt style
+// (e.g. "pip-24.0"). An empty version is returned when the file does not
+// exist.
+func (p PipVersionParser) ParseVersion(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+
+		return "", fmt.Errorf("failed to open %s: %w", PipVersionFile, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		return strings.TrimPrefix(line, "pip-"), nil
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", PipVersionFile, err)
+	}
+
+	return "", nil
+}
@@ -0,0 +1,109 @@
+package pip_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paketo-buildpacks/pip"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testPipVersionParser(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		workingDir string
+		path       string
+
+		parser pip.PipVersionParser
+	)
+
+	it.Before(func() {
+		var err error
+		workingDir, err = os.MkdirTemp("", "working-dir")
+		Expect(err).NotTo(HaveOccurred())
+
+		path = filepath.Join(workingDir, ".pip-version")
+
+		parser = pip.NewPipVersionParser()
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(workingDir)).To(Succeed())
+	})
+
+	context("ParseVersion", func() {
+		context("when the file contains a version", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(path, []byte("24.0\n"), 0600)).To(Succeed())
+			})
+
+			it("returns that version", func() {
+				version, err := parser.ParseVersion(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(version).To(Equal("24.0"))
+			})
+		})
+
+		context("when the file contains comments and blank lines", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(path, []byte("# pinned for the build\n\n  23.*  \n24.0\n"), 0600)).To(Succeed())
+			})
+
+			it("returns the first version", func() {
+				version, err := parser.ParseVersion(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(version).To(Equal("23.*"))
+			})
+		})
+
+		context("when the version is written in the runtime.txt style", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(path, []byte("pip-24.0.1"), 0600)).To(Succeed())
+			})
+
+			it("returns the version without the prefix", func() {
+				version, err := parser.ParseVersion(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(version).To(Equal("24.0.1"))
+			})
+		})
+
+		context("when the file is empty", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(path, nil, 0600)).To(Succeed())
+			})
+
+			it("returns an empty version", func() {
+				version, err := parser.ParseVersion(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(version).To(BeEmpty())
+			})
+		})
+
+		context("when the file does not exist", func() {
+			it("returns an empty version", func() {
+				version, err := parser.ParseVersion(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(version).To(BeEmpty())
+			})
+		})
+
+		context("failure cases", func() {
+			context("when the file cannot be opened", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(path, []byte("24.0"), 0000)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := parser.ParseVersion(path)
+					Expect(err).To(MatchError(ContainSubstring("failed to open .pip-version")))
+					Expect(err).To(MatchError(ContainSubstring("permission denied")))
+				})
+			})
+		})
+	})
+}
@@ -0,0 +1,73 @@
+package pip
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2/pexec"
+)
+
+// pipVersionPattern matches the output of `pip --version`, e.g.
+// "pip 24.0 from /layers/pip/lib/python3.12/site-packages/pip (python 3.12)".
+var pipVersionPattern = regexp.MustCompile(`^pip (\S+) from (.+) \(python [^)]+\)$`)
+
+// PipVersionProcess implements the VersionProcess interface.
+type PipVersionProcess struct {
+	executable Executable
+}
+
+// NewPipVersionProcess creates an instance of the PipVersionProcess given an Executable that runs `python`.
+func NewPipVersionProcess(executable Executable) PipVersionProcess {
+	return PipVersionProcess{
+		executable: executable,
+	}
+}
+
+// Execute runs the pip installed in the targetLayerPath and returns the
+// version it reports. It fails if pip cannot be run, or is not loaded from
+// the targetLayerPath. When the targetLayerPath is empty, the version of the
+// pip bundled with the python interpreter is returned.
+func (p PipVersionProcess) Execute(targetLayerPath string) (string, error) {
+	buffer := bytes.NewBuffer(nil)
+	stdout := bytes.NewBuffer(nil)
+
+	// Set the PYTHONUSERBASE to ensure that the pip from the target layer is
+	// run, or run the python of the virtual environment in the target layer.
+	env := append(os.Environ(), fmt.Sprintf("PYTHONUSERBASE=%s", targetLayerPath))
+	if targetLayerPath == "" {
+		env = os.Environ()
+	} else if _, err := os.Stat(filepath.Join(targetLayerPath, VenvConfigFile)); err == nil {
+		env = venvEnvironment(targetLayerPath)
+	}
+
+	err := p.executable.Execute(pexec.Execution{
+		Args:   []string{"-m", "pip", "--version"},
+		Env:    env,
+		Stdout: stdout,
+		Stderr: buffer,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to run pip:\n%s\nerror: %w", buffer.String(), err)
+	}
+
+	output := strings.TrimSpace(stdout.String())
+	matches := pipVersionPattern.FindStringSubmatch(output)
+	if matches == nil {
+		return "", fmt.Errorf("failed to parse pip version from output: %q", output)
+	}
+
+	if targetLayerPath == "" {
+		return matches[1], nil
+	}
+
+	rel, err := filepath.Rel(targetLayerPath, matches[2])
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("pip was loaded from %s instead of %s", matches[2], targetLayerPath)
+	}
+
+	return matches[1], nil
+}
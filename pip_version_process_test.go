@@ -0,0 +1,144 @@
+package pip_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paketo-buildpacks/packit/v2/pexec"
+	pip "github.com/paketo-buildpacks/pip"
+	"github.com/paketo-buildpacks/pip/fakes"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testPipVersionProcess(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		targetLayerPath string
+		executable      *fakes.Executable
+
+		versionProcess pip.PipVersionProcess
+	)
+
+	it.Before(func() {
+		var err error
+		targetLayerPath, err = os.MkdirTemp("", "pip")
+		Expect(err).NotTo(HaveOccurred())
+
+		executable = &fakes.Executable{}
+		executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+			_, err := fmt.Fprintf(execution.Stdout, "pip 24.0 from %s (python 3.12)\n", filepath.Join(targetLayerPath, "lib", "python3.12", "site-packages", "pip"))
+			Expect(err).NotTo(HaveOccurred())
+			return nil
+		}
+
+		versionProcess = pip.NewPipVersionProcess(executable)
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(targetLayerPath)).To(Succeed())
+	})
+
+	context("Execute", func() {
+		it("returns the version reported by the pip in the layer", func() {
+			version, err := versionProcess.Execute(targetLayerPath)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(executable.ExecuteCall.Receives.Execution.Env).To(Equal(append(os.Environ(), fmt.Sprintf("PYTHONUSERBASE=%s", targetLayerPath))))
+			Expect(executable.ExecuteCall.Receives.Execution.Args).To(Equal([]string{"-m", "pip", "--version"}))
+
+			Expect(version).To(Equal("24.0"))
+		})
+
+		context("no target layer is given", func() {
+			it.Before(func() {
+				executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+					_, err := fmt.Fprintln(execution.Stdout, "pip 23.2.1 from /usr/lib/python3.12/site-packages/pip (python 3.12)")
+					Expect(err).NotTo(HaveOccurred())
+					return nil
+				}
+			})
+
+			it("returns the version of the pip bundled with the interpreter", func() {
+				version, err := versionProcess.Execute("")
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(executable.ExecuteCall.Receives.Execution.Env).To(Equal(os.Environ()))
+				Expect(version).To(Equal("23.2.1"))
+			})
+		})
+
+		context("the pip layer is a virtual environment", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(targetLayerPath, "pyvenv.cfg"), nil, 0600)).To(Succeed())
+			})
+
+			it("runs the python of the virtual environment", func() {
+				version, err := versionProcess.Execute(targetLayerPath)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(executable.ExecuteCall.Receives.Execution.Env).To(ContainElements(
+					fmt.Sprintf("PATH=%s%c%s", filepath.Join(targetLayerPath, "bin"), os.PathListSeparator, os.Getenv("PATH")),
+					fmt.Sprintf("VIRTUAL_ENV=%s", targetLayerPath),
+				))
+				Expect(executable.ExecuteCall.Receives.Execution.Env).NotTo(ContainElement(fmt.Sprintf("PYTHONUSERBASE=%s", targetLayerPath)))
+
+				Expect(version).To(Equal("24.0"))
+			})
+		})
+
+		context("failure cases", func() {
+			context("the pip command fails", func() {
+				it.Before(func() {
+					executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+						_, err := fmt.Fprintln(execution.Stderr, "No module named pip")
+						Expect(err).NotTo(HaveOccurred())
+						return errors.New("exit status 1")
+					}
+				})
+
+				it("returns an error", func() {
+					_, err := versionProcess.Execute(targetLayerPath)
+					Expect(err).To(MatchError(ContainSubstring("failed to run pip:")))
+					Expect(err).To(MatchError(ContainSubstring("No module named pip")))
+					Expect(err).To(MatchError(ContainSubstring("error: exit status 1")))
+				})
+			})
+
+			context("the pip output cannot be parsed", func() {
+				it.Before(func() {
+					executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+						_, err := fmt.Fprintln(execution.Stdout, "unexpected output")
+						Expect(err).NotTo(HaveOccurred())
+						return nil
+					}
+				})
+
+				it("returns an error", func() {
+					_, err := versionProcess.Execute(targetLayerPath)
+					Expect(err).To(MatchError(`failed to parse pip version from output: "unexpected output"`))
+				})
+			})
+
+			context("pip is not loaded from the layer", func() {
+				it.Before(func() {
+					executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+						_, err := fmt.Fprintln(execution.Stdout, "pip 23.0 from /usr/lib/python3/dist-packages/pip (python 3.12)")
+						Expect(err).NotTo(HaveOccurred())
+						return nil
+					}
+				})
+
+				it("returns an error", func() {
+					_, err := versionProcess.Execute(targetLayerPath)
+					Expect(err).To(MatchError(fmt.Sprintf("pip was loaded from /usr/lib/python3/dist-packages/pip instead of %s", targetLayerPath)))
+				})
+			})
+		})
+	})
+}
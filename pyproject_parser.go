@@ -0,0 +1,86 @@
+package pip
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// PyProjectParser implements the VersionParser interface for the
+// pyproject.toml file of an application.
+type PyProjectParser struct{}
+
+// NewPyProjectParser creates an instance of the PyProjectParser.
+func NewPyProjectParser() PyProjectParser {
+	return PyProjectParser{}
+}
+
+// ParseVersion returns the version of pip requested by the pyproject.toml
+// file located at the given path, as written in the file. The
+// [tool.paketo.pip] version takes precedence over the version specifier of a
+// pip entry in the [build-system] requires list. An empty version is returned
+// when the file does not exist, is not valid TOML or does not mention pip, so
+// that it does not prevent detection.
+func (p PyProjectParser) ParseVersion(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+
+		return "", fmt.Errorf("failed to read pyproject.toml: %w", err)
+	}
+
+	// The file is decoded without a schema so that unrelated fields of an
+	// unexpected type do not prevent reading the version of pip.
+	var pyProject map[string]interface{}
+	_, err = toml.Decode(string(content), &pyProject)
+	if err != nil {
+		return "", nil
+	}
+
+	tool, _ := pyProject["tool"].(map[string]interface{})
+	paketo, _ := tool["paketo"].(map[string]interface{})
+	pip, _ := paketo["pip"].(map[string]interface{})
+	if version, _ := pip["version"].(string); strings.TrimSpace(version) != "" {
+		return strings.TrimSpace(version), nil
+	}
+
+	buildSystem, _ := pyProject["build-system"].(map[string]interface{})
+	requires, _ := buildSystem["requires"].([]interface{})
+	for _, entry := range requires {
+		requirement, ok := entry.(string)
+		if !ok {
+			continue
+		}
+
+		name, specifier := splitRequirement(requirement)
+		if name == Pip {
+			return specifier, nil
+		}
+	}
+
+	return "", nil
+}
+
+var (
+	requirementPattern = regexp.MustCompile(`^\s*([A-Za-z0-9][A-Za-z0-9._-]*)\s*(?:\[[^\]]*\])?\s*\(?([^;)]*)\)?\s*(?:;.*)?$`)
+	separatorPattern   = regexp.MustCompile(`[-_.]+`)
+)
+
+// splitRequirement splits a PEP 508 requirement string into its normalized
+// project name and version specifier.
+func splitRequirement(requirement string) (string, string) {
+	matches := requirementPattern.FindStringSubmatch(requirement)
+	if matches == nil {
+		return "", ""
+	}
+
+	name := strings.ToLower(separatorPattern.ReplaceAllString(matches[1], "-"))
+
+	return name, strings.TrimSpace(matches[2])
+}
@@ -0,0 +1,161 @@
+package pip_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paketo-buildpacks/pip"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testPyProjectParser(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		workingDir string
+		path       string
+
+		parser pip.PyProjectParser
+	)
+
+	it.Before(func() {
+		var err error
+		workingDir, err = os.MkdirTemp("", "working-dir")
+		Expect(err).NotTo(HaveOccurred())
+
+		path = filepath.Join(workingDir, "pyproject.toml")
+
+		parser = pip.NewPyProjectParser()
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(workingDir)).To(Succeed())
+	})
+
+	context("ParseVersion", func() {
+		context("when the version is set in [tool.paketo.pip]", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(path, []byte(`
+[build-system]
+requires = ["setuptools>=61", "pip>=23"]
+
+[tool.paketo.pip]
+version = "24.*"
+`), 0600)).To(Succeed())
+			})
+
+			it("returns that version", func() {
+				version, err := parser.ParseVersion(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(version).To(Equal("24.*"))
+			})
+		})
+
+		context("when pip is listed in the [build-system] requires", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(path, []byte(`
+[build-system]
+requires = ["setuptools>=61", "wheel", "pip>=23,<25"]
+`), 0600)).To(Succeed())
+			})
+
+			it("returns the version specifier", func() {
+				version, err := parser.ParseVersion(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(version).To(Equal(">=23,<25"))
+			})
+		})
+
+		context("when the pip requirement is written in other forms", func() {
+			it("returns its version specifier", func() {
+				for requirement, expected := range map[string]string{
+					"pip==24.0":                        "==24.0",
+					"pip == 24.0.1":                    "== 24.0.1",
+					"pip~=24.0":                        "~=24.0",
+					"Pip>=23,!=23.1.*":                 ">=23,!=23.1.*",
+					"pip[extra] (>=23)":                ">=23",
+					`pip>=23; python_version >= "3.8"`: ">=23",
+					"pip":                              "",
+				} {
+					Expect(os.WriteFile(path, []byte(`
+[build-system]
+requires = ['`+requirement+`']
+`), 0600)).To(Succeed())
+
+					version, err := parser.ParseVersion(path)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(version).To(Equal(expected), requirement)
+				}
+			})
+		})
+
+		context("when pip is not mentioned", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(path, []byte(`
+[build-system]
+requires = ["setuptools>=61", "pipenv>=2023"]
+`), 0600)).To(Succeed())
+			})
+
+			it("returns an empty version", func() {
+				version, err := parser.ParseVersion(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(version).To(BeEmpty())
+			})
+		})
+
+		context("when the pyproject.toml does not exist", func() {
+			it("returns an empty version", func() {
+				version, err := parser.ParseVersion(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(version).To(BeEmpty())
+			})
+		})
+
+		context("when the pyproject.toml is malformed", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(path, []byte("%%%"), 0600)).To(Succeed())
+			})
+
+			it("returns an empty version", func() {
+				version, err := parser.ParseVersion(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(version).To(BeEmpty())
+			})
+		})
+
+		context("when other fields of the pyproject.toml have unexpected types", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(path, []byte(`
+[build-system]
+requires = "setuptools"
+
+[tool.paketo.pip]
+version = "24.*"
+`), 0600)).To(Succeed())
+			})
+
+			it("returns the version of pip", func() {
+				version, err := parser.ParseVersion(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(version).To(Equal("24.*"))
+			})
+		})
+
+		context("failure cases", func() {
+			context("when the pyproject.toml cannot be read", func() {
+				it.Before(func() {
+					Expect(os.Mkdir(path, os.ModePerm)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := parser.ParseVersion(path)
+					Expect(err).To(MatchError(ContainSubstring("failed to read pyproject.toml")))
+				})
+			})
+		})
+	})
+}
@@ -0,0 +1,140 @@
+package pip
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/BurntSushi/toml"
+	"github.com/Masterminds/semver/v3"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+)
+
+// buildpackDependency is the subset of a buildpack.toml dependency entry that
+// is needed to check its compatibility with a python interpreter.
+type buildpackDependency struct {
+	ID             string   `toml:"id"`
+	Version        string   `toml:"version"`
+	Stacks         []string `toml:"stacks"`
+	RequiresPython string   `toml:"requires-python"`
+}
+
+// stackDependencies returns the dependencies with the given id in the
+// buildpack.toml at the given path that support the given stack, along with
+// the default version of the dependency from its default-versions.
+func stackDependencies(path, id, stack string) ([]buildpackDependency, string, error) {
+	var config struct {
+		Metadata struct {
+			DefaultVersions map[string]string     `toml:"default-versions"`
+			Dependencies    []buildpackDependency `toml:"dependencies"`
+		} `toml:"metadata"`
+	}
+
+	_, err := toml.DecodeFile(path, &config)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse buildpack.toml: %w", err)
+	}
+
+	var dependencies []buildpackDependency
+	for _, dependency := range config.Metadata.Dependencies {
+		if dependency.ID == id && (slices.Contains(dependency.Stacks, stack) || slices.Contains(dependency.Stacks, "*")) {
+			dependencies = append(dependencies, dependency)
+		}
+	}
+
+	return dependencies, config.Metadata.DefaultVersions[id], nil
+}
+
+// compatibleVersion returns the version of the dependency in the
+// buildpack.toml at the given path to resolve so that it supports the given
+// python version. The requested version is returned unchanged when the newest
+// dependency that matches it supports the python version, or when no
+// dependency matches it so that dependency resolution can report the failure.
+// Otherwise, the newest matching version that supports the python version is
+// returned, and the newer versions that were skipped are logged.
+func compatibleVersion(logger scribe.Emitter, path, id, version, source, stack, pythonVersion string) (string, error) {
+	dependencies, defaultVersion, err := stackDependencies(path, id, stack)
+	if err != nil {
+		return "", err
+	}
+
+	python, err := semver.NewVersion(pythonVersion)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse python version %q: %w", pythonVersion, err)
+	}
+
+	// An empty or default request selects the default version of the
+	// dependency, as in dependency resolution.
+	requested := version
+	if requested == "" || requested == "default" {
+		requested = "*"
+		if defaultVersion != "" {
+			requested = defaultVersion
+		}
+	}
+
+	constraint, err := semver.NewConstraint(requested)
+	if err != nil {
+		return version, nil
+	}
+
+	var candidates []buildpackDependency
+	for _, dependency := range dependencies {
+		dependencyVersion, err := semver.NewVersion(dependency.Version)
+		if err != nil || !constraint.Check(dependencyVersion) {
+			continue
+		}
+
+		candidates = append(candidates, dependency)
+	}
+
+	if len(candidates) == 0 {
+		return version, nil
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return semver.MustParse(candidates[i].Version).GreaterThan(semver.MustParse(candidates[j].Version))
+	})
+
+	for i, candidate := range candidates {
+		supported, err := supportsPython(candidate, python)
+		if err != nil {
+			return "", err
+		}
+
+		if !supported {
+			logger.Subprocess("Skipping pip %s: it requires Python %s, which is not compatible with the installed Python %s", candidate.Version, candidate.RequiresPython, pythonVersion)
+			continue
+		}
+
+		if i == 0 {
+			return version, nil
+		}
+
+		logger.Break()
+		return candidate.Version, nil
+	}
+
+	if version == "" {
+		version = "default"
+	}
+
+	return "", fmt.Errorf("pip %s requires Python %s, which is not compatible with the installed Python %s (pip version %q requested by %s)",
+		candidates[0].Version, candidates[0].RequiresPython, pythonVersion, version, source)
+}
+
+// supportsPython reports whether the requires-python of the given dependency
+// is satisfied by the given python version. Dependencies without a
+// requires-python support any python version.
+func supportsPython(dependency buildpackDependency, python *semver.Version) (bool, error) {
+	if dependency.RequiresPython == "" {
+		return true, nil
+	}
+
+	requiresPython, err := semver.NewConstraint(convertSpecifier(dependency.RequiresPython))
+	if err != nil {
+		return false, fmt.Errorf("failed to parse requires-python %q of pip %s: %w", dependency.RequiresPython, dependency.Version, err)
+	}
+
+	return requiresPython.Check(python), nil
+}
@@ -0,0 +1,81 @@
+package pip
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/paketo-buildpacks/packit/v2/pexec"
+)
+
+// interpreterScript prints a JSON description of the running python
+// interpreter.
+const interpreterScript = `import json, sys, sysconfig
+print(json.dumps({
+    "version": "%d.%d.%d" % sys.version_info[:3],
+    "abi": sysconfig.get_config_var("SOABI") or sys.implementation.cache_tag,
+    "stdlib": sysconfig.get_path("stdlib"),
+}))`
+
+// Interpreter describes the python interpreter that pip is installed for.
+type Interpreter struct {
+	// Version is the X.Y.Z version of the interpreter.
+	Version string `json:"version"`
+
+	// ABI is the ABI tag of the interpreter (e.g. cpython-312-x86_64-linux-gnu).
+	ABI string `json:"abi"`
+
+	// Stdlib is the path to the standard library directory of the interpreter.
+	Stdlib string `json:"stdlib"`
+}
+
+// ExternallyManaged reports whether the interpreter is marked as externally
+// managed (PEP 668), in which case pip refuses to install packages for it
+// unless told otherwise.
+func (i Interpreter) ExternallyManaged() bool {
+	if i.Stdlib == "" {
+		return false
+	}
+
+	info, err := os.Stat(filepath.Join(i.Stdlib, ExternallyManagedFile))
+	return err == nil && !info.IsDir()
+}
+
+// PythonInterpreterProcess implements the InterpreterProcess interface.
+type PythonInterpreterProcess struct {
+	executable Executable
+}
+
+// NewPythonInterpreterProcess creates an instance of the PythonInterpreterProcess given an Executable that runs `python`.
+func NewPythonInterpreterProcess(executable Executable) PythonInterpreterProcess {
+	return PythonInterpreterProcess{
+		executable: executable,
+	}
+}
+
+// Execute runs a python command to describe the python interpreter that is
+// available on the $PATH.
+func (p PythonInterpreterProcess) Execute() (Interpreter, error) {
+	buffer := bytes.NewBuffer(nil)
+	stdout := bytes.NewBuffer(nil)
+
+	err := p.executable.Execute(pexec.Execution{
+		Args:   []string{"-c", interpreterScript},
+		Env:    os.Environ(),
+		Stdout: stdout,
+		Stderr: buffer,
+	})
+	if err != nil {
+		return Interpreter{}, fmt.Errorf("failed to inspect python interpreter:\n%s\nerror: %w", buffer.String(), err)
+	}
+
+	var interpreter Interpreter
+	err = json.Unmarshal(stdout.Bytes(), &interpreter)
+	if err != nil {
+		return Interpreter{}, fmt.Errorf("failed to parse python interpreter details: %w", err)
+	}
+
+	return interpreter, nil
+}
@@ -0,0 +1,111 @@
+package pip_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paketo-buildpacks/packit/v2/pexec"
+	pip "github.com/paketo-buildpacks/pip"
+	"github.com/paketo-buildpacks/pip/fakes"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testPythonInterpreterProcess(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		executable *fakes.Executable
+
+		interpreterProcess pip.PythonInterpreterProcess
+	)
+
+	it.Before(func() {
+		executable = &fakes.Executable{}
+		executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+			_, err := fmt.Fprintln(execution.Stdout, `{"version": "3.12.1", "abi": "cpython-312-x86_64-linux-gnu", "stdlib": "/usr/lib/python3.12"}`)
+			Expect(err).NotTo(HaveOccurred())
+			return nil
+		}
+
+		interpreterProcess = pip.NewPythonInterpreterProcess(executable)
+	})
+
+	context("Execute", func() {
+		it("returns the details of the python interpreter", func() {
+			interpreter, err := interpreterProcess.Execute()
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(executable.ExecuteCall.Receives.Execution.Env).To(Equal(os.Environ()))
+			Expect(executable.ExecuteCall.Receives.Execution.Args[0]).To(Equal("-c"))
+
+			Expect(interpreter).To(Equal(pip.Interpreter{
+				Version: "3.12.1",
+				ABI:     "cpython-312-x86_64-linux-gnu",
+				Stdlib:  "/usr/lib/python3.12",
+			}))
+		})
+
+		context("ExternallyManaged", func() {
+			var stdlibDir string
+
+			it.Before(func() {
+				var err error
+				stdlibDir, err = os.MkdirTemp("", "stdlib")
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			it.After(func() {
+				Expect(os.RemoveAll(stdlibDir)).To(Succeed())
+			})
+
+			it("reports whether the stdlib directory contains the EXTERNALLY-MANAGED marker", func() {
+				interpreter := pip.Interpreter{Stdlib: stdlibDir}
+				Expect(interpreter.ExternallyManaged()).To(BeFalse())
+
+				Expect(os.WriteFile(filepath.Join(stdlibDir, "EXTERNALLY-MANAGED"), nil, 0600)).To(Succeed())
+				Expect(interpreter.ExternallyManaged()).To(BeTrue())
+
+				Expect(pip.Interpreter{}.ExternallyManaged()).To(BeFalse())
+			})
+		})
+
+		context("failure cases", func() {
+			context("the python command fails", func() {
+				it.Before(func() {
+					executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+						_, err := fmt.Fprintln(execution.Stderr, "stderr output")
+						Expect(err).NotTo(HaveOccurred())
+						return errors.New("inspecting python failed")
+					}
+				})
+
+				it("returns an error", func() {
+					_, err := interpreterProcess.Execute()
+					Expect(err).To(MatchError(ContainSubstring("failed to inspect python interpreter:")))
+					Expect(err).To(MatchError(ContainSubstring("stderr output")))
+					Expect(err).To(MatchError(ContainSubstring("error: inspecting python failed")))
+				})
+			})
+
+			context("the python command output cannot be parsed", func() {
+				it.Before(func() {
+					executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+						_, err := fmt.Fprintln(execution.Stdout, "not json")
+						Expect(err).NotTo(HaveOccurred())
+						return nil
+					}
+				})
+
+				it("returns an error", func() {
+					_, err := interpreterProcess.Execute()
+					Expect(err).To(MatchError(ContainSubstring("failed to parse python interpreter details")))
+				})
+			})
+		})
+	})
+}
@@ -0,0 +1,70 @@
+package pip
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// DefaultSourceDateEpoch is the timestamp (1980-01-01T00:00:01Z) used for the
+// files of the pip layer when $SOURCE_DATE_EPOCH is not set. It matches the
+// timestamp the lifecycle uses for the files of exported layers.
+const DefaultSourceDateEpoch = 315532801
+
+// sourceDateEpoch returns the timestamp given by $SOURCE_DATE_EPOCH, in
+// seconds since the Unix epoch, or DefaultSourceDateEpoch when it is not set.
+func sourceDateEpoch() (int64, error) {
+	value, ok := os.LookupEnv("SOURCE_DATE_EPOCH")
+	if !ok || value == "" {
+		return DefaultSourceDateEpoch, nil
+	}
+
+	epoch, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || epoch < 0 {
+		return 0, fmt.Errorf("failed to parse SOURCE_DATE_EPOCH value %q: expected a non-negative number of seconds", value)
+	}
+
+	return epoch, nil
+}
+
+// normalizeModTimes sets the access and modification times of the files and
+// directories under the given path to the given timestamp, so that the
+// contents of the path do not depend on when they were written. Symbolic
+// links are left untouched, since their targets may lie outside of the path.
+func normalizeModTimes(root string, epoch int64) error {
+	timestamp := time.Unix(epoch, 0)
+
+	// Directories are updated after their contents, since writing to a
+	// directory updates its modification time.
+	var dirs []string
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case entry.Type()&fs.ModeSymlink != 0:
+			return nil
+		case entry.IsDir():
+			dirs = append(dirs, path)
+			return nil
+		}
+
+		return os.Chtimes(path, timestamp, timestamp)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to normalize modification times: %w", err)
+	}
+
+	for i := len(dirs) - 1; i >= 0; i-- {
+		err = os.Chtimes(dirs[i], timestamp, timestamp)
+		if err != nil {
+			return fmt.Errorf("failed to normalize modification times: %w", err)
+		}
+	}
+
+	return nil
+}
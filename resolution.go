@@ -0,0 +1,121 @@
+package pip
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+var versionPattern = regexp.MustCompile(`\d+(\.\d+){0,2}`)
+
+// resolutionError explains why the requested version of pip, from the given
+// version source, could not be resolved: it lists the versions of pip in the
+// buildpack.toml at the given path that are available on the stack and
+// support the given python version, and suggests the one closest to the
+// requested version.
+func resolutionError(path, id, version, source, stack, pythonVersion string, err error) error {
+	if version == "" {
+		version = "default"
+	}
+
+	available, parseErr := availableVersions(path, id, stack, pythonVersion)
+	if parseErr != nil || len(available) == 0 {
+		return fmt.Errorf("failed to resolve pip version %q requested by %s: no versions of pip that support Python %s are available on stack %q: %w", version, source, pythonVersion, stack, err)
+	}
+
+	closest := closestVersion(version, available)
+
+	hint := fmt.Sprintf("use %s", closest)
+	if strings.HasPrefix(source, "BP_") {
+		hint = fmt.Sprintf("set %s=%s", source, closest)
+	}
+
+	return fmt.Errorf("failed to resolve pip version %q requested by %s: available versions on stack %q that support Python %s are %s; the closest match is %s (%s): %w",
+		version, source, stack, pythonVersion, strings.Join(available, ", "), closest, hint, err)
+}
+
+// availableVersions returns the versions of the dependency with the given id
+// in the buildpack.toml at the given path that support the given stack and
+// python version, from newest to oldest.
+func availableVersions(path, id, stack, pythonVersion string) ([]string, error) {
+	dependencies, _, err := stackDependencies(path, id, stack)
+	if err != nil {
+		return nil, err
+	}
+
+	python, err := semver.NewVersion(pythonVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse python version %q: %w", pythonVersion, err)
+	}
+
+	var versions []*semver.Version
+	seen := map[string]bool{}
+	for _, dependency := range dependencies {
+		version, err := semver.NewVersion(dependency.Version)
+		if err != nil || seen[dependency.Version] {
+			continue
+		}
+
+		supported, err := supportsPython(dependency, python)
+		if err != nil || !supported {
+			continue
+		}
+		seen[dependency.Version] = true
+
+		versions = append(versions, version)
+	}
+
+	sort.Sort(sort.Reverse(semver.Collection(versions)))
+
+	var available []string
+	for _, version := range versions {
+		available = append(available, version.Original())
+	}
+
+	return available, nil
+}
+
+// closestVersion returns the available version closest to the first version
+// mentioned in the requested version constraint, preferring the closest major,
+// then minor, then patch version, and the newer version on a tie. The newest
+// version is returned when the constraint does not mention a version.
+func closestVersion(requested string, available []string) string {
+	match := versionPattern.FindString(requested)
+	target, err := semver.NewVersion(match)
+	if match == "" || err != nil {
+		return available[0]
+	}
+
+	distance := func(version string) [3]uint64 {
+		v := semver.MustParse(version)
+		return [3]uint64{
+			absDiff(v.Major(), target.Major()),
+			absDiff(v.Minor(), target.Minor()),
+			absDiff(v.Patch(), target.Patch()),
+		}
+	}
+
+	// The available versions are sorted from newest to oldest, so the first of
+	// several equally close versions is the newest.
+	closest := available[0]
+	for _, version := range available[1:] {
+		d, c := distance(version), distance(closest)
+		if slices.Compare(d[:], c[:]) < 0 {
+			closest = version
+		}
+	}
+
+	return closest
+}
+
+func absDiff(a, b uint64) uint64 {
+	if a > b {
+		return a - b
+	}
+
+	return b - a
+}
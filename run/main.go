@@ -10,24 +10,28 @@ import (
 	"github.com/paketo-buildpacks/packit/v2/postal"
 	"github.com/paketo-buildpacks/packit/v2/sbom"
 	"github.com/paketo-buildpacks/packit/v2/scribe"
+	"github.com/paketo-buildpacks/packit/v2/servicebindings"
 	"github.com/paketo-buildpacks/pip"
 )
 
 type Generator struct{}
 
-func (f Generator) GenerateFromDependency(dependency postal.Dependency, path string) (sbom.SBOM, error) {
-	return sbom.GenerateFromDependency(dependency, path)
+func (f Generator) GenerateFromDependencies(dependencies []postal.Dependency, path string) (sbom.SBOM, error) {
+	return pip.GenerateFromDependencies(dependencies, path)
 }
 
 func main() {
 	logger := scribe.NewEmitter(os.Stdout).WithLevel(os.Getenv("BP_LOG_LEVEL"))
 
 	packit.Run(
-		pip.Detect(),
+		pip.Detect(pip.NewPyProjectParser(), pip.NewPipVersionParser()),
 		pip.Build(
 			postal.NewService(cargo.NewTransport()),
-			pip.NewPipInstallProcess(pexec.NewExecutable("python")),
-			pip.NewSiteProcess(pexec.NewExecutable("python")),
+			pip.NewPipInstallProcess(pexec.NewExecutable("python"), logger),
+			pip.NewSiteProcess(pexec.NewExecutable("python"), logger),
+			pip.NewPythonInterpreterProcess(pexec.NewExecutable("python")),
+			pip.NewPipVersionProcess(pexec.NewExecutable("python")),
+			servicebindings.NewResolver(),
 			Generator{},
 			logger,
 			chronos.DefaultClock,
@@ -0,0 +1,63 @@
+package pip
+
+import (
+	"github.com/anchore/syft/syft/cpe"
+	"github.com/anchore/syft/syft/pkg"
+	syftsbom "github.com/anchore/syft/syft/sbom"
+	"github.com/anchore/syft/syft/source"
+	"github.com/paketo-buildpacks/packit/v2/postal"
+	"github.com/paketo-buildpacks/packit/v2/sbom"
+)
+
+// GenerateFromDependencies returns an SBOM listing each of the given
+// dependencies, located in the directory at the given path. It extends
+// sbom.GenerateFromDependency to multiple dependencies, so that the
+// distributions bundled with pip are listed alongside pip itself.
+func GenerateFromDependencies(dependencies []postal.Dependency, path string) (sbom.SBOM, error) {
+	catalog := pkg.NewCollection()
+
+	for _, dependency := range dependencies {
+		cpeStrings := dependency.CPEs
+		//nolint Ignore SA1019, informed usage of deprecated field
+		if len(cpeStrings) == 0 && dependency.CPE != "" {
+			//nolint Ignore SA1019, informed usage of deprecated field
+			cpeStrings = []string{dependency.CPE}
+		}
+		if len(cpeStrings) == 0 {
+			cpeStrings = []string{sbom.UnknownCPE}
+		}
+
+		var cpes []cpe.CPE
+		for _, cpeString := range cpeStrings {
+			c, err := cpe.New(cpeString, cpe.DeclaredSource)
+			if err != nil {
+				return sbom.SBOM{}, err
+			}
+			cpes = append(cpes, c)
+		}
+
+		licenses := pkg.NewLicenseSet()
+		for _, license := range dependency.Licenses {
+			licenses.Add(pkg.NewLicense(license))
+		}
+
+		catalog.Add(pkg.Package{
+			Name:     dependency.Name,
+			Version:  dependency.Version,
+			Licenses: licenses,
+			CPEs:     cpes,
+			PURL:     dependency.PURL,
+		})
+	}
+
+	return sbom.NewSBOM(syftsbom.SBOM{
+		Artifacts: syftsbom.Artifacts{
+			Packages: catalog,
+		},
+		Source: source.Description{
+			Metadata: source.DirectoryMetadata{
+				Path: path,
+			},
+		},
+	}), nil
+}
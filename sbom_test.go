@@ -0,0 +1,75 @@
+package pip_test
+
+import (
+	"io"
+	"testing"
+
+	"github.com/paketo-buildpacks/packit/v2/postal"
+	"github.com/paketo-buildpacks/packit/v2/sbom"
+	pip "github.com/paketo-buildpacks/pip"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testGenerateFromDependencies(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		dependencies []postal.Dependency
+	)
+
+	it.Before(func() {
+		dependencies = []postal.Dependency{
+			{
+				ID:       "pip",
+				Name:     "Pip",
+				Version:  "21.0",
+				CPE:      "cpe:2.3:a:pypa:pip:21.0:*:*:*:*:python:*:*",
+				PURL:     "pkg:generic/pip@21.0?checksum=some-sha&download_url=some-uri",
+				Licenses: []string{"MIT"},
+			},
+			{
+				ID:       "setuptools",
+				Name:     "setuptools",
+				Version:  "69.0.2",
+				PURL:     "pkg:pypi/setuptools@69.0.2",
+				Licenses: []string{"MIT"},
+			},
+			{
+				ID:      "wheel",
+				Name:    "wheel",
+				Version: "0.42.0",
+				PURL:    "pkg:pypi/wheel@0.42.0",
+			},
+		}
+	})
+
+	it("lists every dependency in each SBOM format", func() {
+		bom, err := pip.GenerateFromDependencies(dependencies, "some-path")
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, format := range []sbom.Format{sbom.CycloneDXFormat, sbom.SPDXFormat, sbom.SyftFormat} {
+			content, err := io.ReadAll(sbom.NewFormattedReader(bom, format))
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(string(content)).To(ContainSubstring(`"cpe:2.3:a:pypa:pip:21.0:*:*:*:*:python:*:*"`), string(format))
+			Expect(string(content)).To(ContainSubstring(`"pkg:pypi/setuptools@69.0.2"`), string(format))
+			Expect(string(content)).To(ContainSubstring(`"pkg:pypi/wheel@0.42.0"`), string(format))
+			Expect(string(content)).To(ContainSubstring(`"MIT"`), string(format))
+		}
+	})
+
+	context("failure cases", func() {
+		context("when a CPE is malformed", func() {
+			it.Before(func() {
+				dependencies[1].CPEs = []string{"some-cpe"}
+			})
+
+			it("returns an error", func() {
+				_, err := pip.GenerateFromDependencies(dependencies, "some-path")
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+}
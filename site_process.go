@@ -3,20 +3,25 @@ package pip
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/paketo-buildpacks/packit/v2/pexec"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
 )
 
 // SiteProcess implements the Executable interface.
 type SiteProcess struct {
 	executable Executable
+	logger     scribe.Emitter
 }
 
-// NewSiteProcess creates an instance of the SiteProcess given an Executable that runs `python`
-func NewSiteProcess(executable Executable) SiteProcess {
+// NewSiteProcess creates an instance of the SiteProcess given an Executable that runs `python`.
+// At the debug log level, the output of the Executable is streamed to the logger.
+func NewSiteProcess(executable Executable, logger scribe.Emitter) SiteProcess {
 	return SiteProcess{
 		executable: executable,
+		logger:     logger,
 	}
 }
 
@@ -25,14 +30,17 @@ func (p SiteProcess) Execute(targetLayerPath string) (string, error) {
 	buffer := bytes.NewBuffer(nil)
 	sitePackagesPath := bytes.NewBuffer(nil)
 
-	err := p.executable.Execute(pexec.Execution{
+	execution := pexec.Execution{
 		// Run the python -m site --user-site to locate the user level site-packages.
 		Args: []string{"-m", "site", "--user-site"},
 		// Set the PYTHONUSERBASE to ensure that we are looking at the pip layer for user level packages.
 		Env:    append(os.Environ(), fmt.Sprintf("PYTHONUSERBASE=%s", targetLayerPath)),
-		Stdout: sitePackagesPath,
-		Stderr: buffer,
-	})
+		Stdout: io.MultiWriter(sitePackagesPath, p.logger.Debug.ActionWriter),
+		Stderr: io.MultiWriter(buffer, p.logger.Debug.ActionWriter),
+	}
+	logExecution(p.logger, execution)
+
+	err := p.executable.Execute(execution)
 
 	if err != nil {
 		return "", fmt.Errorf("failed to locate site packages:\n%s\nerror: %w", buffer.String(), err)
@@ -1,6 +1,7 @@
 package pip_test
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"os"
@@ -8,11 +9,13 @@ import (
 	"testing"
 
 	"github.com/paketo-buildpacks/packit/v2/pexec"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
 	pip "github.com/paketo-buildpacks/pip"
 	"github.com/paketo-buildpacks/pip/fakes"
 	"github.com/sclevine/spec"
 
 	. "github.com/onsi/gomega"
+	. "github.com/paketo-buildpacks/packit/v2/matchers"
 )
 
 func testSiteProcess(t *testing.T, context spec.G, it spec.S) {
@@ -21,6 +24,7 @@ func testSiteProcess(t *testing.T, context spec.G, it spec.S) {
 
 		targetLayerPath string
 		executable      *fakes.Executable
+		buffer          *bytes.Buffer
 
 		siteProcess pip.SiteProcess
 	)
@@ -30,6 +34,7 @@ func testSiteProcess(t *testing.T, context spec.G, it spec.S) {
 		targetLayerPath, err = os.MkdirTemp("", "pip")
 		Expect(err).NotTo(HaveOccurred())
 
+		buffer = bytes.NewBuffer(nil)
 		executable = &fakes.Executable{}
 		executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
 			if execution.Stdout != nil {
@@ -39,7 +44,7 @@ func testSiteProcess(t *testing.T, context spec.G, it spec.S) {
 			return nil
 		}
 
-		siteProcess = pip.NewSiteProcess(executable)
+		siteProcess = pip.NewSiteProcess(executable, scribe.NewEmitter(buffer))
 	})
 
 	it.After(func() {
@@ -59,6 +64,24 @@ func testSiteProcess(t *testing.T, context spec.G, it spec.S) {
 			})
 		})
 
+		context("the log level is debug", func() {
+			it.Before(func() {
+				siteProcess = pip.NewSiteProcess(executable, scribe.NewEmitter(buffer).WithLevel("DEBUG"))
+			})
+
+			it("streams the output of the command along with the command line and environment changes", func() {
+				_, err := siteProcess.Execute(targetLayerPath)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).To(ContainLines(
+					"    Running 'python -m site --user-site'",
+					"      With environment:",
+					fmt.Sprintf("        PYTHONUSERBASE=%s", targetLayerPath),
+					fmt.Sprintf("      %s/pip/lib/python/site-packages", targetLayerPath),
+				))
+			})
+		})
+
 		context("failure cases", func() {
 			context("site package lookup fails", func() {
 				it.Before(func() {
@@ -0,0 +1,46 @@
+package pip
+
+import (
+	"fmt"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/paketo-buildpacks/packit/v2/postal"
+)
+
+// systemPipSatisfies reports whether the version of the pip bundled with the
+// python interpreter satisfies the version constraint of the build plan. Any
+// version satisfies an empty or default constraint.
+func systemPipSatisfies(systemVersion, constraint string) bool {
+	version, err := semver.NewVersion(systemVersion)
+	if err != nil {
+		return false
+	}
+
+	if constraint == "" || constraint == "default" {
+		return true
+	}
+
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return false
+	}
+
+	return c.Check(version)
+}
+
+// pipDependency describes a version of pip that does not come from the
+// buildpack.toml (e.g. the pip bundled with the python interpreter) as a
+// dependency, so that it can be listed in the Bill-of-Materials.
+func pipDependency(version string) postal.Dependency {
+	cpe := fmt.Sprintf("cpe:2.3:a:pypa:pip:%s:*:*:*:*:python:*:*", version)
+
+	return postal.Dependency{
+		ID:       Pip,
+		Name:     "Pip",
+		Version:  version,
+		CPE:      cpe,
+		CPEs:     []string{cpe},
+		PURL:     fmt.Sprintf("pkg:pypi/pip@%s", version),
+		Licenses: []string{"MIT"},
+	}
+}